@@ -60,6 +60,59 @@ var joggerArt = [][]string{
 	},
 }
 
+// Colour masks for the jogger frames: one palette key per glyph (see sprite.go).
+// h = hat, s = skin, j = jersey (the runner's own colour), k = shorts and shoes.
+var joggerMask = [][]string{
+	{ // Frame 1
+		"   hhhh  ",
+		"  ssssss ",
+		" sjjjjjjs ",
+		" s j  j s",
+		" skkkkkks",
+		"  k    k ",
+		" kkkkkkkk",
+		"         ",
+	},
+	{ // Frame 2
+		"   hhhh  ",
+		"  ssssss ",
+		" sjjjjjjs ",
+		" s j  j s",
+		" skkkkkks",
+		"  k    k ",
+		" kkkkkkkk",
+		"         ",
+	},
+	{ // Frame 3
+		"   hhhh  ",
+		"  ssssss ",
+		" sjjjjjjs ",
+		" s j  j s",
+		" skkkkkks",
+		"  k    k ",
+		" kkkkkkkk",
+		"         ",
+	},
+	{ // Frame 4
+		"   hhhh  ",
+		"  ssssss ",
+		" sjjjjjjs ",
+		" s j  j s",
+		" skkkkkks",
+		"  k    k ",
+		" kkkkkkkk",
+		"         ",
+	},
+}
+
+// builtinPalette maps the mask keys used by the built-in art to colours.
+var builtinPalette = map[rune]string{
+	'h': "196", // Red cap
+	's': "223", // Skin
+	'j': jerseyColor,
+	'k': "245", // Gray shorts and shoes
+}
+
 // --- Define similar large, multi-frame placeholders for other runner types ---
 // --- TrailRunner (e.g., add backpack, different terrain under feet) ---
 var trailRunnerArt = joggerArt // Placeholder - Copy jogger for now
@@ -86,12 +139,97 @@ var runnerArtMap = map[RunnerType][][]string{
 	TenKRunner:  tenKRunnerArt,  // Update with specific art
 }
 
+// Masks for the built-in art. The placeholders all share the jogger frames
+// for now, so they share its mask too; a mask that doesn't fit the art is ignored.
+var runnerMaskMap = map[RunnerType][][]string{
+	Jogger:      joggerMask,
+	TrailRunner: joggerMask,
+	Marathoner:  joggerMask,
+	CrewRunner:  joggerMask,
+	UltraRunner: joggerMask,
+	TenKRunner:  joggerMask,
+}
+
+// --- Smaller sizes ---
+//
+// Built-in art for the medium and tiny sizes, drawn instead of a runner's
+// large art when that doesn't fit (see resize.go). It's the same for every
+// type: at three lines or fewer there isn't room to tell them apart.
+
+var mediumArt = [][]string{
+	{ // Frame 1
+		" o ",
+		"/|\\",
+		"/ \\",
+	},
+	{ // Frame 2
+		" o ",
+		"\\|/",
+		" | ",
+	},
+}
+
+var mediumMask = [][]string{
+	{
+		" s ",
+		"sjs",
+		"k k",
+	},
+	{
+		" s ",
+		"sjs",
+		" k ",
+	},
+}
+
+var tinyArt = [][]string{
+	{"o>"}, // Frame 1
+	{"o}"}, // Frame 2
+}
+
+var tinyMask = [][]string{
+	{"sj"},
+	{"sj"},
+}
+
 // Function to get art for a runner type
 func getArtForType(rt RunnerType) [][]string {
-	art, ok := runnerArtMap[rt]
-	if !ok || len(art) == 0 || len(art[0]) == 0 { // Check art validity
+	return getSpriteForType(rt).Frames
+}
+
+// getSpriteForType returns the art, colour masks and palette for a runner
+// type at its largest size.
+func getSpriteForType(rt RunnerType) *Sprite {
+	return getSpriteVariant(rt, sizeLarge)
+}
+
+// getSpriteVariant returns a runner type's art at one size. Sprites loaded
+// from disk take precedence over the built-in art above.
+func getSpriteVariant(rt RunnerType, size spriteSize) *Sprite {
+	if s, ok := lookupLoadedArt(rt, size); ok && len(s.Frames) > 0 && len(s.Frames[0]) > 0 {
+		return s
+	}
+	art, masks := runnerArtMap[rt], runnerMaskMap[rt]
+	switch size {
+	case sizeMedium:
+		art, masks = mediumArt, mediumMask
+	case sizeTiny:
+		art, masks = tinyArt, tinyMask
+	}
+	if len(art) == 0 || len(art[0]) == 0 { // Check art validity
 		// Return a default single-frame, single-line error indicator
-		return [][]string{{"?"}}
+		return &Sprite{Type: rt, Size: size, Frames: [][]string{{"?"}}}
+	}
+
+	s := &Sprite{Type: rt, Size: size, Frames: art, Masks: masks, Palette: builtinPalette}
+	if len(s.Masks) != len(s.Frames) {
+		s.Masks = nil
+	}
+	for i := range s.Masks {
+		if checkMask(s, i) != nil {
+			s.Masks = nil // Art was changed without updating its mask
+			break
+		}
 	}
-	return art
+	return s
 }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- asciicast recording (--record, consolerunner record) ---
+//
+// Recordings are asciinema asciicast v2 files: a JSON header line followed by
+// one JSON array per event, [seconds, "o", output] for output and
+// [seconds, "r", "WxH"] for resizes. Each event is a complete frame, timed by
+// the frame's tick rather than by when it reached the terminal.
+// See https://docs.asciinema.org/manual/asciicast/v2/.
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// castRecorder writes frames to an asciicast file. The header is written with
+// the first terminal size, so nothing is recorded before that.
+type castRecorder struct {
+	w       *bufio.Writer
+	start   time.Time
+	started bool
+	last    string // Previous frame; identical frames aren't recorded again
+	err     error  // First write error; later writes are skipped
+}
+
+// newCastRecorder returns a recorder writing to w.
+func newCastRecorder(w io.Writer) *castRecorder {
+	return &castRecorder{w: bufio.NewWriter(w)}
+}
+
+// resize records the terminal size at, writing the header if this is the first.
+func (r *castRecorder) resize(width, height int, at time.Time) {
+	if !r.started {
+		r.started, r.start = true, at
+		r.writeLine(castHeader{
+			Version:   2,
+			Width:     width,
+			Height:    height,
+			Timestamp: at.Unix(),
+			Env:       map[string]string{"TERM": "xterm-256color"},
+		})
+		return
+	}
+	r.event(at, "r", fmt.Sprintf("%dx%d", width, height))
+	r.last = "" // Redraw in full at the new size
+}
+
+// frame records a rendered view shown at at.
+func (r *castRecorder) frame(view string, at time.Time) {
+	if !r.started || view == r.last {
+		return
+	}
+	r.last = view
+	r.event(at, "o", redraw(view))
+}
+
+// redraw returns the output that replaces a terminal's screen with a view:
+// home the cursor and redraw everything. Raw terminals need \r\n.
+func redraw(view string) string {
+	return "\x1b[H\x1b[2J" + strings.ReplaceAll(view, "\n", "\r\n")
+}
+
+// event writes one event line, timed relative to the header.
+func (r *castRecorder) event(at time.Time, code, data string) {
+	seconds := at.Sub(r.start).Seconds()
+	if seconds < 0 {
+		seconds = 0
+	}
+	r.writeLine([]interface{}{json.Number(fmt.Sprintf("%.6f", seconds)), code, data})
+}
+
+// writeLine writes v as a line of JSON.
+func (r *castRecorder) writeLine(v interface{}) {
+	if r.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err == nil {
+		data = append(data, '\n')
+		_, err = r.w.Write(data)
+	}
+	r.err = err
+}
+
+// Close flushes the recording and reports the first error, if any.
+func (r *castRecorder) Close() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.w.Flush()
+}
+
+// recordCast runs a headless model for duration at fps frames per second,
+// recording every frame. Frames go through Update like live ones, so runner
+// positions are interpolated between ticks exactly as on screen.
+func recordCast(m model, rec *castRecorder, start time.Time, fps int, duration time.Duration) error {
+	m.recorder = rec
+	rec.resize(m.termWidth, m.termHeight, start)
+	frameDelay := time.Second / time.Duration(fps)
+	for t := time.Duration(0); t <= duration; t += frameDelay {
+		updated, _ := m.Update(tickMsg(start.Add(t)))
+		m = updated.(model)
+	}
+	return rec.Close()
+}
+
+// runRecord implements "consolerunner record".
+func runRecord(args []string) int {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config file")
+	profile := fs.String("profile", "", "named profile from the config file to use")
+	spriteDir := fs.String("sprites", "", "sprite directory (default from config)")
+	width := fs.Int("width", defaultHeadlessWidth, "terminal width in cells")
+	height := fs.Int("height", defaultHeadlessHeight, "terminal height in cells")
+	seed := fs.Int64("seed", 1, "random seed; the same seed gives the same recording")
+	duration := fs.Duration("duration", 10*time.Second, "length of the recording")
+	fps := fs.Int("fps", defaultFPS, "frames per second")
+	ansi := fs.String("ansi", "256", "colours: none, 16, 256 or truecolor")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner record [flags] <out.cast | ->")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *width <= 0 || *height <= 0 || *fps <= 0 || *duration < 0 {
+		fmt.Fprintln(os.Stderr, "--width, --height and --fps must be greater than zero and --duration must not be negative")
+		return 2
+	}
+	colorProfile, ok := headlessProfiles[*ansi]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid --ansi %q (want none, 16, 256 or truecolor)\n", *ansi)
+		return 2
+	}
+
+	scene, err := loadHeadlessScene(*configPath, *profile, *spriteDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	out := os.Stdout
+	if path := fs.Arg(0); path != "-" {
+		if out, err = os.Create(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't create recording: %v\n", err)
+			return 1
+		}
+	}
+	m := newHeadlessModel(scene, colorProfile, *seed, *width, *height)
+	err = recordCast(m, newCastRecorder(out), time.Now(), *fps, *duration)
+	if out != os.Stdout {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't write recording: %v\n", err)
+		return 1
+	}
+	return 0
+}
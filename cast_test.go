@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muesli/termenv"
+)
+
+// parseCast splits a recording into its header and events.
+func parseCast(t *testing.T, data string) (castHeader, [][]interface{}) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	var header castHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("bad header %q: %v", lines[0], err)
+	}
+	var events [][]interface{}
+	for _, line := range lines[1:] {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil || len(event) != 3 {
+			t.Fatalf("bad event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return header, events
+}
+
+func TestCastRecorder(t *testing.T) {
+	var out bytes.Buffer
+	rec := newCastRecorder(&out)
+	start := time.Unix(1700000000, 0)
+
+	rec.frame("ignored", start) // Nothing is recorded before the size is known
+	rec.resize(10, 2, start)
+	rec.frame("a\nb", start.Add(100*time.Millisecond))
+	rec.frame("a\nb", start.Add(200*time.Millisecond)) // Unchanged, so skipped
+	rec.resize(12, 3, start.Add(250*time.Millisecond))
+	rec.frame("a\nb", start.Add(300*time.Millisecond)) // Redrawn after a resize
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	header, events := parseCast(t, out.String())
+	if header.Version != 2 || header.Width != 10 || header.Height != 2 || header.Timestamp != 1700000000 {
+		t.Errorf("header = %+v, want version 2, 10x2 at 1700000000", header)
+	}
+	want := [][]interface{}{
+		{0.1, "o", "\x1b[H\x1b[2Ja\r\nb"},
+		{0.25, "r", "12x3"},
+		{0.3, "o", "\x1b[H\x1b[2Ja\r\nb"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %v", len(events), len(want), events)
+	}
+	for i := range want {
+		if events[i][0] != want[i][0] || events[i][1] != want[i][1] || events[i][2] != want[i][2] {
+			t.Errorf("event %d = %q, want %q", i, events[i], want[i])
+		}
+	}
+}
+
+func TestRecordCastIsTimedByTicks(t *testing.T) {
+	var out bytes.Buffer
+	m := newHeadlessModel(defaultScene(), termenv.ANSI256, 3, 40, 12)
+	if err := recordCast(m, newCastRecorder(&out), time.Now(), 10, 2*time.Second); err != nil {
+		t.Fatalf("recordCast() error = %v", err)
+	}
+	_, events := parseCast(t, out.String())
+	if len(events) < 10 {
+		t.Fatalf("got %d frames, want about 20", len(events))
+	}
+	last := -1.0
+	for i, e := range events {
+		at := e[0].(float64)
+		if at <= last || at > 2 {
+			t.Errorf("frame %d at %vs, want increasing times within 2s", i, at)
+		}
+		last = at
+	}
+}
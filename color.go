@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// --- Colour arithmetic ---
+//
+// Colours are lipgloss colour strings throughout: an ANSI index ("0"-"255")
+// or "#rrggbb"/"#rgb". These helpers convert them to RGB so they can be
+// blended into gradients and checked for contrast.
+
+// rgb is a colour with 0-255 channels.
+type rgb struct{ R, G, B uint8 }
+
+// ansi16 approximates the 16 system colours (xterm defaults); terminals
+// differ, but it's close enough to judge contrast.
+var ansi16 = [16]rgb{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// parseColor converts a lipgloss colour string to RGB.
+func parseColor(s string) (rgb, error) {
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return rgb{}, fmt.Errorf("invalid color %q", s)
+		}
+		return rgb{uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 {
+		return rgb{}, fmt.Errorf("invalid color %q", s)
+	}
+	switch {
+	case n < 16:
+		return ansi16[n], nil
+	case n < 232:
+		// 6x6x6 colour cube
+		n -= 16
+		level := func(i int) uint8 {
+			if i == 0 {
+				return 0
+			}
+			return uint8(55 + 40*i)
+		}
+		return rgb{level(n / 36), level(n / 6 % 6), level(n % 6)}, nil
+	}
+	// Greyscale ramp
+	grey := uint8(8 + 10*(n-232))
+	return rgb{grey, grey, grey}, nil
+}
+
+// hex formats the colour as "#rrggbb".
+func (c rgb) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// blend returns the colour t (0..1) of the way from c to o.
+func (c rgb) blend(o rgb, t float64) rgb {
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return rgb{mix(c.R, o.R), mix(c.G, o.G), mix(c.B, o.B)}
+}
+
+// luminance is the WCAG relative luminance (0 for black, 1 for white).
+func (c rgb) luminance() float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+// contrastRatio is the WCAG contrast ratio between two colours, from 1
+// (identical) to 21 (black on white).
+func contrastRatio(a, b rgb) float64 {
+	la, lb := a.luminance(), b.luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want rgb
+	}{
+		{"#ff8800", rgb{255, 136, 0}},
+		{"#f80", rgb{255, 136, 0}},
+		{"9", rgb{255, 0, 0}},       // Bright red system colour
+		{"16", rgb{0, 0, 0}},        // Cube origin
+		{"196", rgb{255, 0, 0}},     // Cube red
+		{"110", rgb{135, 175, 215}}, // Cube 2,3,4
+		{"232", rgb{8, 8, 8}},       // Greyscale ramp start
+		{"255", rgb{238, 238, 238}},
+	}
+	for _, tt := range tests {
+		got, err := parseColor(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseColor(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"256", "-1", "#12345", "orange"} {
+		if _, err := parseColor(bad); err == nil {
+			t.Errorf("parseColor(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	black, white := rgb{0, 0, 0}, rgb{255, 255, 255}
+	if got := contrastRatio(black, white); math.Abs(got-21) > 0.01 {
+		t.Errorf("contrastRatio(black, white) = %v, want 21", got)
+	}
+	if got := contrastRatio(white, black); math.Abs(got-21) > 0.01 {
+		t.Errorf("contrastRatio(white, black) = %v, want 21", got)
+	}
+	if got := contrastRatio(black, black); got != 1 {
+		t.Errorf("contrastRatio(black, black) = %v, want 1", got)
+	}
+}
+
+func TestThemeJerseysAreReadable(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for name, theme := range colorThemes {
+		for _, dark := range []bool{false, true} {
+			for i := 0; i < 50; i++ {
+				if c := theme.pickJersey(rng, dark); !theme.readable(c, dark) {
+					t.Errorf("%s (dark %v) picked jersey %s with too little contrast", name, dark, c)
+				}
+			}
+		}
+		// Themes with a sky choose their own jerseys, so all of them should work
+		if len(theme.Sky) > 0 {
+			for _, c := range theme.Jerseys {
+				if !theme.readable(c, true) {
+					t.Errorf("%s jersey %s is too close to its sky", name, c)
+				}
+			}
+		}
+	}
+}
+
+func TestSkyGradient(t *testing.T) {
+	theme := colorTheme{Sky: []string{"#000000", "#ffffff"}}
+	if got := theme.skyColor(0, 5, true); got != "#000000" {
+		t.Errorf("top = %s, want #000000", got)
+	}
+	if got := theme.skyColor(2, 5, true); got != "#808080" {
+		t.Errorf("middle = %s, want #808080", got)
+	}
+	if got := theme.skyColor(4, 5, true); got != "#ffffff" {
+		t.Errorf("bottom = %s, want #ffffff", got)
+	}
+	if got := theme.skyColor(4, 5, false); got != "#000000" {
+		t.Errorf("bottom without truecolor = %s, want the solid #000000", got)
+	}
+	if got := colorThemes[colorThemeClassic].skyColor(0, 5, true); got != "" {
+		t.Errorf("classic sky = %q, want the terminal background", got)
+	}
+}
+
+func TestColorThemeChangeKeepsConfiguredColors(t *testing.T) {
+	scene := defaultScene()
+	scene.Runners = []RunnerConfig{{Color: "#ff8800"}, {}}
+	m := initialModel(scene, display{})
+
+	scene.ColorTheme = colorThemeNeon
+	m.applyScene(scene)
+	if got := m.runners[0].Color.Dark; got != "#ff8800" {
+		t.Errorf("configured jersey = %s, want #ff8800", got)
+	}
+	if got := m.runners[1].Color.Dark; !contains(colorThemes[colorThemeNeon].Jerseys, got) {
+		t.Errorf("random jersey = %s, want one of the neon jerseys", got)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
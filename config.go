@@ -0,0 +1,480 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// --- Configuration file ---
+//
+// The config file lives at ~/.config/consolerunner/config.toml (or wherever
+// os.UserConfigDir points) and describes a scene. Top-level keys set the
+// defaults; [profile.<name>] tables override them when selected with --profile.
+//
+//	fps = 30
+//	tick_rate = "100ms"
+//	theme = "dark"          # Terminal background: auto, light or dark
+//	color_theme = "neon"    # classic, solarized, neon or mono
+//	background = ["sun", "mountains", "birds"]
+//	render = "halfblock"    # text, halfblock or braille
+//	lanes = 3               # Runner art is sized to fit a third of the height
+//	sprite_dir = "sprites"  # Relative to the config file
+//
+//	[layer_depth]           # Runners are at depth 0
+//	mountains = 5           # In front of the runners
+//
+//	[keys]
+//	quit = ["q", "esc"]
+//
+//	[[runner]]
+//	type = "marathoner"
+//	name = "Ana"
+//	color = "#ff8800"
+//	z = 1                   # In front of other runners
+//
+//	[profile.office]
+//	fps = 10
+//	background = []
+
+// Scene holds the settings that both the top level of the config file and
+// each profile may set. Zero values mean "not set" so profiles can be layered.
+type Scene struct {
+	FPS        int                 `toml:"fps"`
+	TickRate   time.Duration       `toml:"tick_rate"`
+	Theme      themeName           `toml:"theme"`
+	Background []backgroundLayer   `toml:"background"`
+	Runners    []RunnerConfig      `toml:"runner"`
+	Keys       map[string][]string `toml:"keys"`
+	SpriteDir  string              `toml:"sprite_dir"`
+	LayerDepth map[string]int      `toml:"layer_depth"` // Layer name -> depth; runners default to 0
+	Render     renderMode          `toml:"render"`
+	ColorTheme colorThemeName      `toml:"color_theme"`
+	Lanes      int                 `toml:"lanes"` // Runners use the largest art that fits height/lanes
+}
+
+// Config is the decoded config file: a base scene plus named profiles.
+type Config struct {
+	Scene
+	Profiles map[string]Scene `toml:"profile"`
+}
+
+// RunnerConfig describes one entry of a fixed runner roster.
+type RunnerConfig struct {
+	Type  *RunnerType `toml:"type"`  // nil picks a random type
+	Name  string      `toml:"name"`  // Optional display name
+	Color colorValue  `toml:"color"` // Empty picks a random colour
+	Speed float64     `toml:"speed"` // Cells per tick; 0 picks a random speed
+	Z     int         `toml:"z"`     // Depth; higher is drawn in front
+}
+
+// themeName selects which side of each runner's AdaptiveColor is used.
+type themeName string
+
+const (
+	themeAuto  themeName = "auto"  // Ask the terminal
+	themeLight themeName = "light" // Light background
+	themeDark  themeName = "dark"  // Dark background
+)
+
+// UnmarshalText validates theme names while decoding.
+func (t *themeName) UnmarshalText(text []byte) error {
+	switch name := themeName(strings.ToLower(string(text))); name {
+	case themeAuto, themeLight, themeDark:
+		*t = name
+		return nil
+	}
+	return fmt.Errorf("unknown theme %q (want auto, light or dark)", string(text))
+}
+
+// colorThemeName names one of the colour themes in theme.go.
+type colorThemeName string
+
+const (
+	colorThemeClassic   colorThemeName = "classic"
+	colorThemeSolarized colorThemeName = "solarized"
+	colorThemeNeon      colorThemeName = "neon"
+	colorThemeMono      colorThemeName = "mono"
+)
+
+// UnmarshalText validates colour theme names while decoding (and for --color-theme).
+func (c *colorThemeName) UnmarshalText(text []byte) error {
+	switch name := colorThemeName(strings.ToLower(string(text))); name {
+	case colorThemeClassic, colorThemeSolarized, colorThemeNeon, colorThemeMono:
+		*c = name
+		return nil
+	}
+	return fmt.Errorf("unknown color theme %q (want classic, solarized, neon or mono)", string(text))
+}
+
+// renderMode selects how the scene is drawn to the terminal (see hires.go).
+type renderMode string
+
+const (
+	renderText      renderMode = "text"      // One sprite glyph per cell
+	renderHalfBlock renderMode = "halfblock" // One glyph per half cell, drawn with ▀ and ▄
+	renderBraille   renderMode = "braille"   // One glyph per braille dot, 2x4 per cell
+)
+
+// UnmarshalText validates render mode names while decoding (and for --render).
+func (r *renderMode) UnmarshalText(text []byte) error {
+	switch name := renderMode(strings.ToLower(string(text))); name {
+	case renderText, renderHalfBlock, renderBraille:
+		*r = name
+		return nil
+	}
+	return fmt.Errorf("unknown render mode %q (want text, halfblock or braille)", string(text))
+}
+
+// backgroundLayer names one of the static scenery elements drawn behind runners.
+type backgroundLayer string
+
+const (
+	layerSun       backgroundLayer = "sun"
+	layerMountains backgroundLayer = "mountains"
+	layerBirds     backgroundLayer = "birds"
+)
+
+// UnmarshalText validates background layer names while decoding.
+func (l *backgroundLayer) UnmarshalText(text []byte) error {
+	switch name := backgroundLayer(strings.ToLower(string(text))); name {
+	case layerSun, layerMountains, layerBirds:
+		*l = name
+		return nil
+	}
+	return fmt.Errorf("unknown background layer %q (want sun, mountains or birds)", string(text))
+}
+
+// colorValue is a lipgloss colour string: an ANSI index (0-255) or #rrggbb.
+type colorValue string
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// UnmarshalText validates colour strings while decoding.
+func (c *colorValue) UnmarshalText(text []byte) error {
+	s := string(text)
+	if hexColorPattern.MatchString(s) {
+		*c = colorValue(s)
+		return nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		*c = colorValue(s)
+		return nil
+	}
+	return fmt.Errorf("invalid color %q (want an ANSI index 0-255 or #rrggbb)", s)
+}
+
+// defaultScene is what you get with no config file at all.
+func defaultScene() Scene {
+	return Scene{
+		FPS:        defaultFPS,
+		TickRate:   tickSpeed,
+		Theme:      themeAuto,
+		Background: []backgroundLayer{layerSun, layerMountains, layerBirds},
+		SpriteDir:  defaultSpriteDir(),
+		Render:     renderText,
+		ColorTheme: colorThemeClassic,
+		Lanes:      1,
+	}
+}
+
+// merge returns s with every field that is set in o overriding it.
+// Key bindings are merged per action rather than replaced wholesale.
+func (s Scene) merge(o Scene) Scene {
+	if o.FPS != 0 {
+		s.FPS = o.FPS
+	}
+	if o.TickRate != 0 {
+		s.TickRate = o.TickRate
+	}
+	if o.Theme != "" {
+		s.Theme = o.Theme
+	}
+	if o.Background != nil {
+		s.Background = o.Background
+	}
+	if o.Runners != nil {
+		s.Runners = o.Runners
+	}
+	if o.SpriteDir != "" {
+		s.SpriteDir = o.SpriteDir
+	}
+	if o.Render != "" {
+		s.Render = o.Render
+	}
+	if o.ColorTheme != "" {
+		s.ColorTheme = o.ColorTheme
+	}
+	if o.Lanes != 0 {
+		s.Lanes = o.Lanes
+	}
+	if len(o.LayerDepth) > 0 {
+		merged := make(map[string]int, len(s.LayerDepth)+len(o.LayerDepth))
+		for l, depth := range s.LayerDepth {
+			merged[l] = depth
+		}
+		for l, depth := range o.LayerDepth {
+			merged[l] = depth
+		}
+		s.LayerDepth = merged
+	}
+	if len(o.Keys) > 0 {
+		merged := make(map[string][]string, len(s.Keys)+len(o.Keys))
+		for action, ks := range s.Keys {
+			merged[action] = ks
+		}
+		for action, ks := range o.Keys {
+			merged[action] = ks
+		}
+		s.Keys = merged
+	}
+	return s
+}
+
+// defaultConfigPath returns the per-user config file location.
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "consolerunner", "config.toml"), nil
+}
+
+// resolveConfigPath returns the config file to read: path itself if given,
+// otherwise the default location. explicit reports whether path was given.
+func resolveConfigPath(path string) (resolved string, explicit bool) {
+	if path != "" {
+		return path, true
+	}
+	resolved, err := defaultConfigPath()
+	if err != nil {
+		return "", false // No home directory; just use defaults
+	}
+	return resolved, false
+}
+
+// loadScene reads the config file at path (or the default location when path
+// is empty) and returns the scene for the given profile layered over the
+// built-in defaults. A missing default config file is not an error.
+func loadScene(path, profile string) (Scene, error) {
+	path, explicit := resolveConfigPath(path)
+	if path == "" {
+		return defaultScene(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			if profile != "" {
+				return Scene{}, fmt.Errorf("profile %q requested but no config file at %s", profile, path)
+			}
+			return defaultScene(), nil
+		}
+		return Scene{}, err
+	}
+
+	cfg, err := parseConfig(path, data)
+	if err != nil {
+		return Scene{}, err
+	}
+
+	scene := defaultScene().merge(cfg.Scene)
+	if profile != "" {
+		p, ok := cfg.Profiles[profile]
+		if !ok {
+			return Scene{}, fmt.Errorf("%s: unknown profile %q (available: %s)", path, profile, profileNames(cfg))
+		}
+		scene = scene.merge(p)
+	}
+	return scene, nil
+}
+
+// parseConfig decodes and validates config file contents. Errors are prefixed
+// with "file:line:" wherever the offending line can be determined.
+func parseConfig(path string, data []byte) (Config, error) {
+	var cfg Config
+	md, err := toml.Decode(string(data), &cfg)
+	if err != nil {
+		var perr toml.ParseError
+		if errors.As(err, &perr) {
+			return Config{}, fmt.Errorf("%s:%d: %s", path, perr.Position.Line, perr.Message)
+		}
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		key := undecoded[0]
+		return Config{}, configErrorAt(path, data, key, 0, fmt.Sprintf("unknown key %q", key.String()))
+	}
+
+	if err := validateScene(path, data, md, nil, cfg.Scene); err != nil {
+		return Config{}, err
+	}
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Report errors deterministically
+	for _, name := range names {
+		if err := validateScene(path, data, md, toml.Key{"profile", name}, cfg.Profiles[name]); err != nil {
+			return Config{}, err
+		}
+	}
+
+	// Relative sprite directories are relative to the config file itself
+	cfg.SpriteDir = resolveRelative(path, cfg.SpriteDir)
+	for name, p := range cfg.Profiles {
+		p.SpriteDir = resolveRelative(path, p.SpriteDir)
+		cfg.Profiles[name] = p
+	}
+	return cfg, nil
+}
+
+// validateScene checks constraints that the decoder can't express on its own.
+// md says which keys were given, as a zero value given isn't the same as
+// one left out.
+func validateScene(path string, data []byte, md toml.MetaData, prefix toml.Key, s Scene) error {
+	at := func(key toml.Key, nth int, format string, args ...interface{}) error {
+		full := append(append(toml.Key{}, prefix...), key...)
+		return configErrorAt(path, data, full, nth, fmt.Sprintf(format, args...))
+	}
+	given := func(key ...string) bool {
+		return md.IsDefined(append(append([]string{}, prefix...), key...)...)
+	}
+
+	if s.FPS < 0 || s.FPS > maxFPS || s.FPS == 0 && given("fps") {
+		return at(toml.Key{"fps"}, 0, "fps must be between 1 and %d, got %d", maxFPS, s.FPS)
+	}
+	if s.Lanes < 0 {
+		return at(toml.Key{"lanes"}, 0, "lanes must be at least 1, got %d", s.Lanes)
+	}
+	if s.TickRate < 0 {
+		return at(toml.Key{"tick_rate"}, 0, "tick_rate must be positive, got %s", s.TickRate)
+	}
+	for i, rc := range s.Runners {
+		if rc.Speed < 0 {
+			return at(toml.Key{"runner", "speed"}, i, "runner speed must not be negative, got %v", rc.Speed)
+		}
+	}
+	layers := make([]string, 0, len(s.LayerDepth))
+	for name := range s.LayerDepth {
+		layers = append(layers, name)
+	}
+	sort.Strings(layers)
+	for _, name := range layers {
+		if _, ok := defaultLayerDepth[backgroundLayer(name)]; !ok {
+			return at(toml.Key{"layer_depth", name}, 0, "unknown background layer %q in layer_depth", name)
+		}
+	}
+	bindable := keys.bindings()
+	actions := make([]string, 0, len(s.Keys))
+	for action := range s.Keys {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions) // Report errors deterministically
+	for _, action := range actions {
+		if _, ok := bindable[action]; !ok {
+			return at(toml.Key{"keys", action}, 0, "unknown key binding action %q", action)
+		}
+		if len(s.Keys[action]) == 0 {
+			return at(toml.Key{"keys", action}, 0, "key binding %q needs at least one key", action)
+		}
+	}
+	return nil
+}
+
+// configErrorAt formats an error for the nth (0-based) definition of key,
+// prefixed with the line it appears on.
+func configErrorAt(path string, data []byte, key toml.Key, nth int, msg string) error {
+	if line := findKeyLine(data, key, nth); line > 0 {
+		return fmt.Errorf("%s:%d: %s", path, line, msg)
+	}
+	return fmt.Errorf("%s: %s", path, msg)
+}
+
+// findKeyLine makes a best-effort scan for the line of the nth (0-based)
+// definition of key, tracking [table] and [[array]] headers as it goes; nth
+// matters for keys inside arrays of tables. It returns 0 if the key isn't
+// found. The toml package only reports positions for syntax and decode
+// errors, so we need this for errors found during validation.
+func findKeyLine(data []byte, key toml.Key, nth int) int {
+	want := strings.Join(key, ".")
+	seen := 0
+	var table []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			header := strings.Trim(line[:strings.LastIndex(line, "]")+1], "[]")
+			table = splitKey(header)
+			if strings.Join(table, ".") == want {
+				if seen == nth {
+					return i + 1
+				}
+				seen++
+			}
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			continue
+		}
+		full := append(append([]string{}, table...), splitKey(line[:eq])...)
+		if strings.Join(full, ".") == want {
+			if seen == nth {
+				return i + 1
+			}
+			seen++
+		}
+	}
+	return 0
+}
+
+// splitKey splits a dotted TOML key, dropping whitespace and quotes.
+func splitKey(s string) []string {
+	parts := strings.Split(s, ".")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"'`)
+	}
+	return parts
+}
+
+// resolveRelative expands a leading ~ and makes dir relative to the directory
+// containing configPath. Empty values stay empty ("not set").
+func resolveRelative(configPath, dir string) string {
+	if dir == "" {
+		return ""
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[1:])
+		}
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(configPath), dir)
+}
+
+// profileNames lists the profiles defined in cfg, for error messages.
+func profileNames(cfg Config) string {
+	if len(cfg.Profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const testConfig = `fps = 20
+theme = "dark"
+
+[keys]
+quit = ["x"]
+
+[[runner]]
+type = "trail-runner"
+name = "Ana"
+color = "#ff8800"
+
+[[runner]]
+name = "Bo"
+speed = 1.5
+
+[profile.office]
+fps = 5
+tick_rate = "200ms"
+background = []
+
+[profile.marathon]
+background = ["mountains"]
+
+[[profile.marathon.runner]]
+type = "Marathoner"
+`
+
+// writeConfig writes contents to a temporary config file and returns its path.
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSceneProfiles(t *testing.T) {
+	path := writeConfig(t, testConfig)
+
+	base, err := loadScene(path, "")
+	if err != nil {
+		t.Fatalf("loadScene() error = %v", err)
+	}
+	if base.FPS != 20 || base.Theme != themeDark || base.TickRate != tickSpeed {
+		t.Errorf("base scene = fps %d theme %q tick %v, want 20 dark %v", base.FPS, base.Theme, base.TickRate, tickSpeed)
+	}
+	if len(base.Runners) != 2 || *base.Runners[0].Type != TrailRunner || base.Runners[1].Type != nil {
+		t.Errorf("base roster = %+v, want a TrailRunner and a random runner", base.Runners)
+	}
+	if len(base.Background) != 3 {
+		t.Errorf("base background = %v, want the default layers", base.Background)
+	}
+
+	office, err := loadScene(path, "office")
+	if err != nil {
+		t.Fatalf("loadScene(office) error = %v", err)
+	}
+	if office.FPS != 5 || office.TickRate != 200*time.Millisecond || office.Theme != themeDark {
+		t.Errorf("office scene = fps %d tick %v theme %q, want 5 200ms dark", office.FPS, office.TickRate, office.Theme)
+	}
+	if office.Background == nil || len(office.Background) != 0 {
+		t.Errorf("office background = %#v, want explicitly empty", office.Background)
+	}
+
+	marathon, err := loadScene(path, "marathon")
+	if err != nil {
+		t.Fatalf("loadScene(marathon) error = %v", err)
+	}
+	if len(marathon.Runners) != 1 || *marathon.Runners[0].Type != Marathoner {
+		t.Errorf("marathon roster = %+v, want one Marathoner", marathon.Runners)
+	}
+
+	if _, err := loadScene(path, "beach"); err == nil || !strings.Contains(err.Error(), "marathon, office") {
+		t.Errorf("loadScene(beach) error = %v, want unknown profile listing the available ones", err)
+	}
+}
+
+func TestParseConfigErrorsHaveLineNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "syntax error",
+			contents: "fps = 30\ntheme = \n",
+			want:     "config.toml:2:",
+		},
+		{
+			name:     "unknown runner type",
+			contents: "[[runner]]\nname = \"a\"\n\n[[runner]]\ntype = \"sprinter\"\n",
+			want:     `config.toml:5: unknown runner type "sprinter"`,
+		},
+		{
+			name:     "bad colour",
+			contents: "[[runner]]\ncolor = \"orange\"\n",
+			want:     `config.toml:2: invalid color "orange"`,
+		},
+		{
+			name:     "unknown key",
+			contents: "fps = 30\n\n[profile.office]\nfsp = 10\n",
+			want:     `config.toml:4: unknown key "profile.office.fsp"`,
+		},
+		{
+			name:     "negative speed on second runner",
+			contents: "[[runner]]\nspeed = 1.0\n[[runner]]\nspeed = -1.0\n",
+			want:     "config.toml:4: runner speed must not be negative",
+		},
+		{
+			name:     "unknown key action",
+			contents: "[keys]\nquit = [\"q\"]\nfly = [\"space\"]\n",
+			want:     `config.toml:3: unknown key binding action "fly"`,
+		},
+		{
+			name:     "bad layer depth",
+			contents: "fps = 30\n\n[layer_depth]\nsun = 1\nmoon = 2\n",
+			want:     `config.toml:5: unknown background layer "moon"`,
+		},
+		{
+			name:     "bad render mode",
+			contents: "fps = 30\nrender = \"ascii\"\n",
+			want:     `config.toml:2: unknown render mode "ascii"`,
+		},
+		{
+			name:     "zero fps in profile",
+			contents: "fps = 30\n\n[profile.slow]\ntheme = \"dark\"\nfps = 0\n",
+			want:     "config.toml:5: fps must be between 1 and 240, got 0",
+		},
+		{
+			name:     "huge fps",
+			contents: "fps = 1000000000\n",
+			want:     "config.toml:1: fps must be between 1 and 240",
+		},
+		{
+			name:     "bad layer in profile",
+			contents: "[profile.night]\nbackground = [\"moon\"]\n",
+			want:     `config.toml:2: unknown background layer "moon"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig("config.toml", []byte(tt.contents))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseConfig() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeyMapOverrides(t *testing.T) {
+	km := newKeyMap(map[string][]string{"quit": {"x", "esc"}})
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}}, km.Quit) {
+		t.Error("overridden quit binding doesn't match 'x'")
+	}
+	if key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}, km.Quit) {
+		t.Error("overridden quit binding still matches the default 'q'")
+	}
+	if km.Quit.Help().Desc != "quit" {
+		t.Errorf("help description = %q, want it preserved", km.Quit.Help().Desc)
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}, keys.Quit) {
+		t.Error("newKeyMap modified the default key map")
+	}
+}
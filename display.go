@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// --- Terminal capabilities ---
+//
+// What the terminal can show is detected once at startup (--color and
+// --charset override the detection) and passed to the model, rather than
+// asked for on every frame. With no colour the scene is drawn as plain text;
+// on terminals that can't show Unicode, other glyphs are replaced with ASCII
+// look-alikes and the high-resolution render modes fall back to text.
+
+// colorMode is the value of --color.
+type colorMode string
+
+const (
+	colorAuto   colorMode = "auto"   // Detect, honouring NO_COLOR
+	colorNever  colorMode = "never"  // Plain text
+	colorAlways colorMode = "always" // Colour even when not writing to a terminal
+)
+
+// charsetMode is the value of --charset.
+type charsetMode string
+
+const (
+	charsetAuto    charsetMode = "auto"    // Detect from the locale and TERM
+	charsetUnicode charsetMode = "unicode" // Any glyph
+	charsetASCII   charsetMode = "ascii"   // Printable ASCII only
+)
+
+// display describes the terminal the scene is drawn on. The zero value is a
+// truecolor Unicode terminal with a light background.
+type display struct {
+	profile termenv.Profile // Colour support; termenv.Ascii for none
+	dark    bool            // The terminal background is dark
+	ascii   bool            // Only ASCII glyphs can be shown
+}
+
+// parseColorMode validates a --color value.
+func parseColorMode(s string) (colorMode, error) {
+	switch mode := colorMode(strings.ToLower(s)); mode {
+	case colorAuto, colorNever, colorAlways:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown color mode %q (want auto, never or always)", s)
+}
+
+// parseCharsetMode validates a --charset value.
+func parseCharsetMode(s string) (charsetMode, error) {
+	switch mode := charsetMode(strings.ToLower(s)); mode {
+	case charsetAuto, charsetUnicode, charsetASCII:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown charset %q (want auto, unicode or ascii)", s)
+}
+
+// detectDisplay works out what stdout can show.
+func detectDisplay(color colorMode, charset charsetMode) display {
+	out := termenv.NewOutput(os.Stdout)
+	d := display{
+		profile: chooseProfile(color, out.ColorProfile(), os.Getenv),
+		ascii:   chooseASCII(charset, os.Getenv),
+	}
+	if d.profile != termenv.Ascii {
+		d.dark = out.HasDarkBackground() // Asks the terminal, so only when it matters
+	}
+	return d
+}
+
+// envDisplay works out what a remote terminal can show from its
+// environment (TERM, COLORTERM, NO_COLOR, the locale), for sessions that
+// have no terminal of their own to ask. The background is assumed to be
+// dark unless COLORFGBG says otherwise.
+func envDisplay(env termenv.Environ) display {
+	out := termenv.NewOutput(io.Discard, termenv.WithEnvironment(env), termenv.WithTTY(true))
+	d := display{
+		profile: chooseProfile(colorAuto, out.ColorProfile(), env.Getenv),
+		ascii:   chooseASCII(charsetAuto, env.Getenv),
+		dark:    true,
+	}
+	if fgbg := env.Getenv("COLORFGBG"); fgbg != "" {
+		bg := fgbg[strings.LastIndex(fgbg, ";")+1:]
+		d.dark = bg != "7" && bg != "15" // White and bright white; the rest are dark
+	}
+	return d
+}
+
+// chooseProfile picks the colour profile for --color given what the terminal
+// reports it supports.
+func chooseProfile(mode colorMode, detected termenv.Profile, getenv func(string) string) termenv.Profile {
+	switch mode {
+	case colorNever:
+		return termenv.Ascii
+	case colorAlways:
+		if detected == termenv.Ascii {
+			return termenv.ANSI256 // Not a terminal, or TERM says no colour
+		}
+		return detected
+	}
+	if getenv("NO_COLOR") != "" { // https://no-color.org
+		return termenv.Ascii
+	}
+	return detected
+}
+
+// chooseASCII reports whether to stick to ASCII glyphs for --charset. In auto
+// mode that's when the locale isn't UTF-8 or the terminal is a dumb one.
+func chooseASCII(mode charsetMode, getenv func(string) string) bool {
+	switch mode {
+	case charsetUnicode:
+		return false
+	case charsetASCII:
+		return true
+	}
+	if getenv("TERM") == "dumb" {
+		return true
+	}
+	// The first locale variable that is set wins, as in setlocale(3)
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+		}
+	}
+	return false // No locale at all; most terminals are UTF-8 these days
+}
+
+// renderer returns a lipgloss renderer that styles for this display.
+func (d display) renderer() *lipgloss.Renderer {
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(d.profile)
+	r.SetHasDarkBackground(d.dark)
+	return r
+}
+
+// asciiFallbacks maps common non-ASCII glyphs to ASCII look-alikes.
+var asciiFallbacks = map[rune]rune{
+	'─': '-', '━': '-', '═': '-', '│': '|', '┃': '|', '║': '|',
+	'┌': '+', '┐': '+', '└': '+', '┘': '+', '├': '+', '┤': '+', '┬': '+', '┴': '+', '┼': '+',
+	'╭': '+', '╮': '+', '╰': '+', '╯': '+', '╱': '/', '╲': '\\',
+	'▀': '"', '▄': ',', '█': '#', '▌': '[', '▐': ']', '░': ':', '▒': '%', '▓': '#',
+	'•': '*', '·': '.', '…': '.', '°': 'o', '☀': '*',
+	'‘': '\'', '’': '\'', '“': '"', '”': '"', '–': '-', '—': '-',
+}
+
+// asciiGlyph returns an ASCII stand-in for a glyph.
+func asciiGlyph(r rune) rune {
+	if r < 0x80 {
+		return r
+	}
+	if a, ok := asciiFallbacks[r]; ok {
+		return a
+	}
+	if r >= 0x2801 && r <= 0x28ff {
+		return '.' // Braille with some dots set
+	}
+	if r == 0x2800 {
+		return ' ' // Blank braille
+	}
+	return '?'
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muesli/termenv"
+)
+
+// env returns a getenv function backed by a map.
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func TestChooseProfile(t *testing.T) {
+	noColor := env(map[string]string{"NO_COLOR": "1"})
+	tests := []struct {
+		name     string
+		mode     colorMode
+		detected termenv.Profile
+		getenv   func(string) string
+		want     termenv.Profile
+	}{
+		{"auto uses the terminal", colorAuto, termenv.ANSI256, env(nil), termenv.ANSI256},
+		{"auto honours NO_COLOR", colorAuto, termenv.TrueColor, noColor, termenv.Ascii},
+		{"never", colorNever, termenv.TrueColor, env(nil), termenv.Ascii},
+		{"always overrides NO_COLOR", colorAlways, termenv.TrueColor, noColor, termenv.TrueColor},
+		{"always without a terminal", colorAlways, termenv.Ascii, env(nil), termenv.ANSI256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chooseProfile(tt.mode, tt.detected, tt.getenv); got != tt.want {
+				t.Errorf("chooseProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChooseASCII(t *testing.T) {
+	tests := []struct {
+		name string
+		mode charsetMode
+		vars map[string]string
+		want bool
+	}{
+		{"utf-8 locale", charsetAuto, map[string]string{"LANG": "en_US.UTF-8"}, false},
+		{"C locale", charsetAuto, map[string]string{"LANG": "C"}, true},
+		{"LC_ALL wins", charsetAuto, map[string]string{"LC_ALL": "POSIX", "LANG": "en_US.utf8"}, true},
+		{"no locale", charsetAuto, nil, false},
+		{"dumb terminal", charsetAuto, map[string]string{"TERM": "dumb", "LANG": "en_US.UTF-8"}, true},
+		{"forced unicode", charsetUnicode, map[string]string{"LANG": "C"}, false},
+		{"forced ascii", charsetASCII, map[string]string{"LANG": "en_US.UTF-8"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chooseASCII(tt.mode, env(tt.vars)); got != tt.want {
+				t.Errorf("chooseASCII() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// profileScene returns a small scene with scenery and one masked runner,
+// rendered for d.
+func profileScene(d display) string {
+	scene := defaultScene()
+	scene.ColorTheme = colorThemeSolarized
+	jogger := Jogger
+	scene.Runners = []RunnerConfig{{Type: &jogger, Color: "#ff8800"}}
+	m := initialModel(scene, d)
+	m.termWidth, m.termHeight = 40, 12
+	m.runners[0].Pos = Position{X: 5, Y: 2}
+	m.runners[0].PrevPos = m.runners[0].Pos
+	return m.View()
+}
+
+func TestEnvDisplay(t *testing.T) {
+	tests := []struct {
+		name string
+		env  sshEnv
+		want display
+	}{
+		{"256 colours", sshEnv{"TERM": "xterm-256color", "LANG": "en_US.UTF-8"}, display{profile: termenv.ANSI256, dark: true}},
+		{"truecolor", sshEnv{"TERM": "xterm", "COLORTERM": "truecolor", "LANG": "C.UTF-8"}, display{profile: termenv.TrueColor, dark: true}},
+		{"light background", sshEnv{"TERM": "xterm-256color", "COLORFGBG": "0;15", "LANG": "C.UTF-8"}, display{profile: termenv.ANSI256}},
+		{"no colour", sshEnv{"TERM": "xterm-256color", "NO_COLOR": "1", "LANG": "C.UTF-8"}, display{profile: termenv.Ascii, dark: true}},
+		{"ascii locale", sshEnv{"TERM": "xterm", "LANG": "C"}, display{profile: termenv.ANSI, dark: true, ascii: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := envDisplay(tt.env); got != tt.want {
+				t.Errorf("envDisplay() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewUnderEachProfile(t *testing.T) {
+	tests := []struct {
+		profile termenv.Profile
+		want    []string // Escape sequences that must appear
+		notWant []string // ... and that must not
+	}{
+		{termenv.TrueColor, []string{"38;2;"}, nil},
+		{termenv.ANSI256, []string{"38;5;"}, []string{"38;2;"}},
+		{termenv.ANSI, []string{"\x1b["}, []string{"38;2;", "38;5;"}},
+		{termenv.Ascii, nil, []string{"\x1b"}},
+	}
+	plain := profileScene(display{profile: termenv.Ascii})
+	for _, tt := range tests {
+		t.Run(tt.profile.Name(), func(t *testing.T) {
+			view := profileScene(display{profile: tt.profile, dark: true})
+			for _, s := range tt.want {
+				if !strings.Contains(view, s) {
+					t.Errorf("view doesn't contain %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(view, s) {
+					t.Errorf("view contains %q", s)
+				}
+			}
+			// Colour never changes the glyphs
+			if stripped := stripANSI(view); stripped != plain {
+				t.Errorf("glyphs differ from the uncoloured view:\n%s\nwant:\n%s", stripped, plain)
+			}
+		})
+	}
+}
+
+// stripANSI removes SGR escape sequences.
+func stripANSI(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+func TestASCIIDisplay(t *testing.T) {
+	b := newFrameBuffer(6, 1)
+	b.drawLine(0, 0, "█─日x", 0, fixedColor(""))
+	if got := b.render(display{profile: termenv.Ascii, ascii: true}); got != "#-??x " {
+		t.Errorf("render() = %q, want %q", got, "#-??x ")
+	}
+
+	// The high-resolution modes need Unicode, so they fall back to text
+	scene := defaultScene()
+	scene.Render = renderBraille
+	if m := initialModel(scene, display{ascii: true}); m.render != renderText {
+		t.Errorf("render mode = %q, want text on an ASCII terminal", m.render)
+	}
+}
@@ -0,0 +1,594 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Sprite editor (consolerunner edit <sprite>) ---
+//
+// A small grid editor for drawing runner frames. Every printable key types a
+// glyph, so all editor commands are on control keys. Frames with a colour mask
+// can switch to editing the mask layer, where keys type palette keys instead;
+// the palette itself is edited in the sprite file's header.
+
+const (
+	defaultEditorWidth  = 10
+	defaultEditorHeight = 8
+
+	editorJerseyPreview = "205" // Stands in for the runner's colour while editing
+)
+
+// editorTickMsg advances the live preview.
+type editorTickMsg time.Time
+
+// editorKeyMap holds the editor's key bindings.
+type editorKeyMap struct {
+	Up, Down, Left, Right key.Binding
+	Backspace, Delete     key.Binding
+	NextFrame, PrevFrame  key.Binding
+	AddFrame, DupFrame    key.Binding
+	DeleteFrame           key.Binding
+	OnionSkin             key.Binding
+	ToggleLayer           key.Binding
+	Save                  key.Binding
+	Quit                  key.Binding
+}
+
+var editorKeys = editorKeyMap{
+	Up:          key.NewBinding(key.WithKeys("up"), key.WithHelp("↑", "up")),
+	Down:        key.NewBinding(key.WithKeys("down"), key.WithHelp("↓", "down")),
+	Left:        key.NewBinding(key.WithKeys("left"), key.WithHelp("←", "left")),
+	Right:       key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "right")),
+	Backspace:   key.NewBinding(key.WithKeys("backspace"), key.WithHelp("bksp", "erase left")),
+	Delete:      key.NewBinding(key.WithKeys("delete"), key.WithHelp("del", "erase")),
+	NextFrame:   key.NewBinding(key.WithKeys("tab", "pgdown"), key.WithHelp("tab", "next frame")),
+	PrevFrame:   key.NewBinding(key.WithKeys("shift+tab", "pgup"), key.WithHelp("shift+tab", "prev frame")),
+	AddFrame:    key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("^n", "new frame")),
+	DupFrame:    key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("^d", "duplicate")),
+	DeleteFrame: key.NewBinding(key.WithKeys("ctrl+x"), key.WithHelp("^x", "delete frame")),
+	OnionSkin:   key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("^o", "onion skin")),
+	ToggleLayer: key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("^t", "art/mask")),
+	Save:        key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("^s", "save")),
+	Quit:        key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "quit")),
+}
+
+// editorModel is the Bubble Tea model for the sprite editor.
+type editorModel struct {
+	path        string
+	spriteType  RunnerType
+	size        spriteSize // Preserved from the sprite file
+	frames      [][][]rune // frames[f][y][x], a rune per cell; 0 is covered by a wide glyph to its left
+	masks       [][][]rune // Colour mask per frame, same shape, keyed at each glyph's cell; nil for single-colour frames
+	palette     map[rune]string
+	transparent rune // Preserved from the sprite file
+	width       int
+	height      int
+	editMask    bool // Typing edits the mask layer instead of the art
+
+	frame      int // Frame being edited
+	cursorX    int
+	cursorY    int
+	onionSkin  bool
+	previewIdx int // Frame shown in the live preview
+
+	modified     bool
+	confirmQuit  bool // Set after esc with unsaved changes; a second esc quits
+	status       string
+	keys         editorKeyMap
+	termWidth    int
+	termHeight   int
+	previewDelay time.Duration
+}
+
+// newEditorModel creates an editor for the given sprite. Frames may be
+// ragged; they are padded with spaces to a common grid of cells, measured
+// as the renderer does, so a wide glyph takes two.
+func newEditorModel(path string, s *Sprite) editorModel {
+	e := editorModel{
+		path:         path,
+		spriteType:   s.Type,
+		size:         s.Size,
+		palette:      s.Palette,
+		transparent:  s.Transparent,
+		onionSkin:    true,
+		keys:         editorKeys,
+		previewDelay: tickSpeed,
+	}
+	for _, frame := range s.Frames {
+		if len(frame) > e.height {
+			e.height = len(frame)
+		}
+		for _, line := range frame {
+			if w := lipgloss.Width(line); w > e.width {
+				e.width = w
+			}
+		}
+	}
+	// A sprite of blank lines still gets a cell to draw in
+	if e.width == 0 {
+		e.width = 1
+	}
+	if e.height == 0 {
+		e.height = 1
+	}
+	for i, frame := range s.Frames {
+		grid := e.gridFromLines(frame)
+		e.frames = append(e.frames, grid)
+		var mask [][]rune
+		if i < len(s.Masks) && s.Masks[i] != nil {
+			mask = e.maskFromLines(grid, s.Masks[i])
+		}
+		e.masks = append(e.masks, mask)
+	}
+	if e.palette == nil {
+		e.palette = map[rune]string{}
+	}
+	return e
+}
+
+// gridFromLines converts frame lines into a width x height grid of cells.
+func (e editorModel) gridFromLines(lines []string) [][]rune {
+	grid := e.blankGrid()
+	for y, line := range lines {
+		x := 0
+		for _, r := range line {
+			grid[y][x] = r
+			for w := lipgloss.Width(string(r)); w > 1; w-- {
+				x++
+				grid[y][x] = 0
+			}
+			x++
+		}
+	}
+	return grid
+}
+
+// maskFromLines converts mask lines, a key per glyph, into a grid with each
+// key in the cell of its glyph in art.
+func (e editorModel) maskFromLines(art [][]rune, lines []string) [][]rune {
+	grid := e.blankGrid()
+	for y, line := range lines {
+		keys := []rune(line)
+		for x := 0; x < e.width && len(keys) > 0; x++ {
+			if art[y][x] != 0 {
+				grid[y][x], keys = keys[0], keys[1:]
+			}
+		}
+	}
+	return grid
+}
+
+// blankGrid returns a width x height grid of spaces.
+func (e editorModel) blankGrid() [][]rune {
+	grid := make([][]rune, e.height)
+	for y := range grid {
+		grid[y] = []rune(strings.Repeat(" ", e.width))
+	}
+	return grid
+}
+
+// copyGrid returns a deep copy of a frame grid.
+func copyGrid(grid [][]rune) [][]rune {
+	if grid == nil {
+		return nil
+	}
+	out := make([][]rune, len(grid))
+	for y, row := range grid {
+		out[y] = append([]rune(nil), row...)
+	}
+	return out
+}
+
+// sprite converts the editor state back into a Sprite. Lines keep their
+// trailing spaces so every line of every frame has the same width.
+func (e editorModel) sprite() *Sprite {
+	s := &Sprite{Type: e.spriteType, Size: e.size, Palette: e.palette, Transparent: e.transparent}
+	hasMask := false
+	for i, grid := range e.frames {
+		s.Frames = append(s.Frames, gridLines(grid, grid))
+		s.Masks = append(s.Masks, gridLines(e.masks[i], grid))
+		hasMask = hasMask || e.masks[i] != nil
+	}
+	if !hasMask {
+		s.Masks = nil
+	}
+	return s
+}
+
+// gridLines converts a grid (the art or its mask) back into lines, a rune
+// per glyph of art, leaving out the cells wide glyphs cover. nil stays nil.
+func gridLines(grid, art [][]rune) []string {
+	if grid == nil {
+		return nil
+	}
+	lines := make([]string, len(grid))
+	for y, row := range grid {
+		var line []rune
+		for x, r := range row {
+			if art[y][x] != 0 {
+				line = append(line, r)
+			}
+		}
+		lines[y] = string(line)
+	}
+	return lines
+}
+
+// Init starts the preview animation.
+func (e editorModel) Init() tea.Cmd {
+	return e.previewTick()
+}
+
+func (e editorModel) previewTick() tea.Cmd {
+	return tea.Tick(e.previewDelay, func(t time.Time) tea.Msg {
+		return editorTickMsg(t)
+	})
+}
+
+// Update handles editor input.
+func (e editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		e.termWidth = msg.Width
+		e.termHeight = msg.Height
+		return e, nil
+
+	case editorTickMsg:
+		e.previewIdx = (e.previewIdx + 1) % len(e.frames)
+		return e, e.previewTick()
+
+	case tea.KeyMsg:
+		quitting := key.Matches(msg, e.keys.Quit)
+		if !quitting {
+			e.confirmQuit = false
+		}
+		switch {
+		case quitting:
+			if e.modified && !e.confirmQuit {
+				e.confirmQuit = true
+				e.status = "Unsaved changes! Press esc again to quit without saving, ^s to save."
+				return e, nil
+			}
+			return e, tea.Quit
+		case key.Matches(msg, e.keys.Save):
+			if err := saveSprite(e.path, e.sprite()); err != nil {
+				e.status = fmt.Sprintf("Save failed: %v", err)
+			} else {
+				e.modified = false
+				e.status = fmt.Sprintf("Saved %s", e.path)
+			}
+		case key.Matches(msg, e.keys.Up):
+			e.moveCursor(0, -1)
+		case key.Matches(msg, e.keys.Down):
+			e.moveCursor(0, 1)
+		case key.Matches(msg, e.keys.Left):
+			e.moveCursor(-1, 0)
+		case key.Matches(msg, e.keys.Right):
+			e.moveCursor(1, 0)
+		case key.Matches(msg, e.keys.Backspace):
+			if e.cursorX > 0 {
+				e.cursorX--
+				e.setGlyph(' ')
+			}
+		case key.Matches(msg, e.keys.Delete):
+			e.setGlyph(' ')
+		case key.Matches(msg, e.keys.NextFrame):
+			e.frame = (e.frame + 1) % len(e.frames)
+		case key.Matches(msg, e.keys.PrevFrame):
+			e.frame = (e.frame - 1 + len(e.frames)) % len(e.frames)
+		case key.Matches(msg, e.keys.AddFrame):
+			e.insertFrame(e.blankGrid(), nil)
+		case key.Matches(msg, e.keys.DupFrame):
+			e.insertFrame(copyGrid(e.frames[e.frame]), copyGrid(e.masks[e.frame]))
+		case key.Matches(msg, e.keys.DeleteFrame):
+			if len(e.frames) == 1 {
+				e.status = "Can't delete the only frame"
+				break
+			}
+			e.frames = append(e.frames[:e.frame], e.frames[e.frame+1:]...)
+			e.masks = append(e.masks[:e.frame], e.masks[e.frame+1:]...)
+			if e.frame >= len(e.frames) {
+				e.frame = len(e.frames) - 1
+			}
+			e.previewIdx %= len(e.frames)
+			e.modified = true
+		case key.Matches(msg, e.keys.OnionSkin):
+			e.onionSkin = !e.onionSkin
+		case key.Matches(msg, e.keys.ToggleLayer):
+			e.editMask = !e.editMask
+			if e.editMask && len(e.palette) == 0 {
+				e.status = "No palette yet: add color.<key> = <color> lines to the sprite header"
+			}
+		case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+			for _, r := range msg.Runes {
+				if lipgloss.Width(string(r)) != 1 {
+					e.status = fmt.Sprintf("%q is not a single-cell glyph", r)
+					continue
+				}
+				if _, ok := e.palette[r]; e.editMask && r != ' ' && !ok {
+					e.status = fmt.Sprintf("%q is not in the palette (add color.%c to the sprite header)", r, r)
+					continue
+				}
+				e.setGlyph(r)
+				e.moveCursor(1, 0)
+			}
+		}
+	}
+	return e, nil
+}
+
+// moveCursor moves the cursor, clamped to the grid.
+func (e *editorModel) moveCursor(dx, dy int) {
+	e.cursorX += dx
+	e.cursorY += dy
+	if e.cursorX < 0 {
+		e.cursorX = 0
+	} else if e.cursorX >= e.width {
+		e.cursorX = e.width - 1
+	}
+	if e.cursorY < 0 {
+		e.cursorY = 0
+	} else if e.cursorY >= e.height {
+		e.cursorY = e.height - 1
+	}
+}
+
+// setGlyph writes a glyph (or, in mask mode, a palette key) at the cursor in
+// the current frame. Painting on a frame without a mask starts a blank one.
+// A glyph written over half of a wide one replaces it, leaving a space in
+// the other half; a key goes on the wide glyph whichever half it's on.
+func (e *editorModel) setGlyph(r rune) {
+	art := e.frames[e.frame]
+	row, x := art[e.cursorY], e.cursorX
+	if e.editMask {
+		if e.masks[e.frame] == nil {
+			e.masks[e.frame] = e.blankGrid()
+		}
+		row = e.masks[e.frame][e.cursorY]
+		for x > 0 && art[e.cursorY][x] == 0 {
+			x--
+		}
+	} else if row[x] != r {
+		for left := x; left > 0 && row[left] == 0; left-- {
+			row[left-1] = ' '
+		}
+		for right := x + 1; right < len(row) && row[right] == 0; right++ {
+			row[right] = ' '
+		}
+	}
+	if row[x] != r {
+		row[x] = r
+		e.modified = true
+	}
+}
+
+// insertFrame inserts a frame (and its mask, which may be nil) after the
+// current one and switches to it.
+func (e *editorModel) insertFrame(grid, mask [][]rune) {
+	e.frames = append(e.frames[:e.frame+1], append([][][]rune{grid}, e.frames[e.frame+1:]...)...)
+	e.masks = append(e.masks[:e.frame+1], append([][][]rune{mask}, e.masks[e.frame+1:]...)...)
+	e.frame++
+	e.modified = true
+}
+
+// glyphStyle returns the style for a glyph of frame f given its mask key,
+// mirroring glyphColor in the main animation.
+func (e editorModel) glyphStyle(f, x, y int) lipgloss.Style {
+	color := editorJerseyPreview
+	if mask := e.masks[f]; mask != nil {
+		color = e.palette[mask[y][x]]
+		if color == jerseyColor {
+			color = editorJerseyPreview
+		}
+	}
+	if color == "" {
+		return lipgloss.NewStyle()
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+}
+
+// View renders the editing grid, the live preview and the status/help lines.
+func (e editorModel) View() string {
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+	onionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240")) // Dim gray
+	frameBox := lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("63"))
+
+	// Editing grid. In art mode the previous frame shows through empty cells;
+	// in mask mode the art shows dimmed wherever the mask is empty.
+	current := e.frames[e.frame]
+	prev := e.frames[(e.frame-1+len(e.frames))%len(e.frames)]
+	mask := e.masks[e.frame]
+	// A wide glyph covers the cell after it; if something narrower is shown
+	// in its place, that cell is blank.
+	var grid strings.Builder
+	for y := 0; y < e.height; y++ {
+		wide := false // The last glyph shown covers this cell
+		for x := 0; x < e.width; x++ {
+			char := current[y][x]
+			style := e.glyphStyle(e.frame, x, y)
+			switch {
+			case e.editMask:
+				style = onionStyle
+				if mask != nil && mask[y][x] != ' ' && char != 0 {
+					char = mask[y][x]
+					style = e.glyphStyle(e.frame, x, y)
+				}
+			case char == ' ' && e.onionSkin && len(e.frames) > 1 && prev[y][x] != ' ' &&
+				lipgloss.Width(string(prev[y][x])) == 1:
+				char = prev[y][x]
+				style = onionStyle
+			}
+			if char == 0 {
+				if wide {
+					wide = false
+					continue
+				}
+				char = ' '
+			}
+			wide = lipgloss.Width(string(char)) > 1
+			if y == e.cursorY && (x == e.cursorX || wide && x+1 == e.cursorX) {
+				style = cursorStyle
+			}
+			grid.WriteString(style.Render(string(char)))
+		}
+		if y < e.height-1 {
+			grid.WriteString("\n")
+		}
+	}
+
+	// Live preview of the animation, in colour
+	var preview strings.Builder
+	for y, row := range e.frames[e.previewIdx] {
+		for x, char := range row {
+			if char != 0 { // Covered by the wide glyph before it
+				preview.WriteString(e.glyphStyle(e.previewIdx, x, y).Render(string(char)))
+			}
+		}
+		if y < e.height-1 {
+			preview.WriteString("\n")
+		}
+	}
+
+	layer := "art"
+	if e.editMask {
+		layer = "mask"
+	}
+	editor := lipgloss.JoinVertical(lipgloss.Left,
+		fmt.Sprintf("Frame %d/%d (%s)", e.frame+1, len(e.frames), layer),
+		frameBox.Render(grid.String()),
+	)
+	previewPane := lipgloss.JoinVertical(lipgloss.Left,
+		"Preview",
+		frameBox.Render(preview.String()),
+	)
+	panes := lipgloss.JoinHorizontal(lipgloss.Top, editor, "  ", previewPane)
+
+	modified := ""
+	if e.modified {
+		modified = " [modified]"
+	}
+	onion := "off"
+	if e.onionSkin {
+		onion = "on"
+	}
+	info := fmt.Sprintf("%s (%s)%s  cursor %d,%d  onion skin %s",
+		e.path, e.spriteType, modified, e.cursorX, e.cursorY, onion)
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	var help []string
+	for _, b := range []key.Binding{e.keys.NextFrame, e.keys.PrevFrame, e.keys.AddFrame, e.keys.DupFrame,
+		e.keys.DeleteFrame, e.keys.OnionSkin, e.keys.ToggleLayer, e.keys.Save, e.keys.Quit} {
+		help = append(help, b.Help().Key+" "+b.Help().Desc)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		panes,
+		"",
+		info,
+		e.status,
+		helpStyle.Render(strings.Join(help, " • ")),
+	)
+}
+
+// --- Command entry point ---
+
+// resolveSpritePath turns the edit argument into a file path. Anything that
+// looks like a path is used as-is; otherwise it names a runner type and maps
+// to <sprite dir>/<type>.sprite.
+func resolveSpritePath(arg, spriteDir string) (string, error) {
+	if strings.ContainsRune(arg, filepath.Separator) || strings.HasSuffix(arg, spriteExt) {
+		return arg, nil
+	}
+	rt, err := parseRunnerType(arg)
+	if err != nil {
+		return "", err
+	}
+	if spriteDir == "" {
+		return "", errors.New("no sprite directory; pass a file path instead")
+	}
+	return filepath.Join(spriteDir, spriteName(rt)+spriteExt), nil
+}
+
+// newSpriteForPath starts a sprite that doesn't exist on disk yet: a copy of
+// the built-in art if the file name names a runner type (and optionally a
+// size, as in "jogger.tiny.sprite"), else a blank frame of the given type.
+func newSpriteForPath(path string, rt RunnerType, width, height int) *Sprite {
+	if named, size, err := parseSpriteFileName(path); err == nil {
+		return &Sprite{Type: named, Size: size, Frames: getSpriteVariant(named, size).Frames}
+	}
+	blank := make([]string, height)
+	for i := range blank {
+		blank[i] = strings.Repeat(" ", width)
+	}
+	return &Sprite{Type: rt, Frames: [][]string{blank}}
+}
+
+// runEdit implements "consolerunner edit <sprite>". It returns the exit code.
+func runEdit(args []string) int {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config file (for sprite_dir)")
+	spriteDir := fs.String("sprites", "", "sprite directory (default from config)")
+	width := fs.Int("width", defaultEditorWidth, "grid width for a brand new sprite")
+	height := fs.Int("height", defaultEditorHeight, "grid height for a brand new sprite")
+	typeName := fs.String("type", "jogger", "runner type for a brand new sprite whose file name isn't a type")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner edit [flags] <runner-type | file.sprite>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *width <= 0 || *height <= 0 {
+		fmt.Fprintln(os.Stderr, "--width and --height must be greater than zero")
+		return 2
+	}
+	rt, err := parseRunnerType(*typeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --type: %v\n", err)
+		return 2
+	}
+
+	dir := *spriteDir
+	if dir == "" {
+		scene, err := loadScene(*configPath, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+			return 2
+		}
+		dir = scene.SpriteDir
+	}
+	path, err := resolveSpritePath(fs.Arg(0), dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't edit %q: %v\n", fs.Arg(0), err)
+		return 2
+	}
+
+	s, err := loadSprite(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s = newSpriteForPath(path, rt, *width, *height)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Sprite error: %v\n", err)
+		return 1
+	}
+
+	p := tea.NewProgram(newEditorModel(path, s), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Alas, there's been an error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// press sends a sequence of key messages to the editor.
+func press(e editorModel, msgs ...tea.KeyMsg) editorModel {
+	for _, msg := range msgs {
+		updated, _ := e.Update(msg)
+		e = updated.(editorModel)
+	}
+	return e
+}
+
+func typed(s string) tea.KeyMsg {
+	if s == " " {
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestEditorDrawAndSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sprites", "jogger.sprite")
+	e := newEditorModel(path, &Sprite{Type: Jogger, Frames: [][]string{{"   ", "   "}}})
+
+	e = press(e,
+		typed("o"), typed(" "), typed("o"),
+		tea.KeyMsg{Type: tea.KeyDown},
+		tea.KeyMsg{Type: tea.KeyCtrlD}, // Duplicate frame 1 into frame 2
+		typed("!"),
+		tea.KeyMsg{Type: tea.KeyCtrlS},
+	)
+	if e.modified {
+		t.Fatalf("still modified after save; status %q", e.status)
+	}
+
+	s, err := loadSprite(path)
+	if err != nil {
+		t.Fatalf("loadSprite() error = %v", err)
+	}
+	want := [][]string{{"o o", "   "}, {"o o", "  !"}}
+	if s.Type != Jogger || !reflect.DeepEqual(s.Frames, want) {
+		t.Errorf("saved sprite = %v %q, want Jogger %q", s.Type, s.Frames, want)
+	}
+}
+
+func TestEditorFrameManagement(t *testing.T) {
+	e := newEditorModel("x.sprite", &Sprite{Type: Jogger, Frames: [][]string{{"a"}, {"b"}}})
+
+	e = press(e, tea.KeyMsg{Type: tea.KeyCtrlN}) // Blank frame after "a"
+	if len(e.frames) != 3 || e.frame != 1 || string(e.frames[1][0]) != " " {
+		t.Fatalf("after ^n frames = %q, current %d", e.frames, e.frame)
+	}
+
+	e = press(e, tea.KeyMsg{Type: tea.KeyCtrlX}, tea.KeyMsg{Type: tea.KeyCtrlX})
+	if len(e.frames) != 1 || string(e.frames[0][0]) != "a" {
+		t.Fatalf("after deleting twice frames = %q", e.frames)
+	}
+	e = press(e, tea.KeyMsg{Type: tea.KeyCtrlX})
+	if len(e.frames) != 1 {
+		t.Error("deleted the only frame")
+	}
+	if e.View() == "" {
+		t.Error("View() rendered nothing")
+	}
+}
+
+func TestEditorBlankSprite(t *testing.T) {
+	s, err := parseSprite("blank.sprite", []byte("type = jogger\n--- frame\n\n"))
+	if err != nil {
+		t.Fatalf("parseSprite() error = %v", err)
+	}
+	e := newEditorModel("blank.sprite", s)
+	e = press(e, typed("o"))
+	if e.width < 1 || e.height < 1 || e.frames[0][0][0] != 'o' {
+		t.Errorf("typing on a blank sprite gave a %dx%d grid %q, want the glyph in its first cell", e.width, e.height, e.frames)
+	}
+}
+
+func TestEditorWideGlyphs(t *testing.T) {
+	sprite := &Sprite{
+		Type:    Jogger,
+		Frames:  [][]string{{"a你b", "abcd"}},
+		Masks:   [][]string{{"jkj", "jjjj"}},
+		Palette: map[rune]string{'j': jerseyColor, 'k': "1"},
+	}
+	e := newEditorModel("x.sprite", sprite)
+	if e.width != 4 {
+		t.Fatalf("grid is %d cells wide, want 4 as the renderer measures it", e.width)
+	}
+	if got := e.sprite(); !reflect.DeepEqual(got.Frames, sprite.Frames) || !reflect.DeepEqual(got.Masks, sprite.Masks) {
+		t.Errorf("sprite() = %q %q, want the frames and masks it was loaded with", got.Frames, got.Masks)
+	}
+	for _, e := range []editorModel{e, press(e, tea.KeyMsg{Type: tea.KeyCtrlT})} {
+		// The grid's right-hand border lines up under the top corner
+		grid := strings.Split(e.View(), "\n")[1:4]
+		for _, row := range grid {
+			end := strings.IndexAny(row[1:], "│┐") + 1
+			if w := lipgloss.Width(row[:end]); w != e.width+1 {
+				t.Errorf("grid rows %q, want the border %d cells in on each", grid, e.width+1)
+				break
+			}
+		}
+	}
+
+	// Typing over either half of the wide glyph replaces it
+	e = press(e, tea.KeyMsg{Type: tea.KeyRight}, tea.KeyMsg{Type: tea.KeyRight}, typed("x"))
+	if got := e.sprite().Frames[0][0]; got != "a xb" {
+		t.Errorf("typing over the wide glyph's second half gave %q, want \"a xb\"", got)
+	}
+}
+
+func TestEditorQuitConfirmsUnsavedChanges(t *testing.T) {
+	e := newEditorModel("x.sprite", &Sprite{Type: Jogger, Frames: [][]string{{"a"}}})
+	e = press(e, typed("b"))
+
+	updated, cmd := e.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd != nil {
+		t.Fatal("first esc with unsaved changes should not quit")
+	}
+	_, cmd = updated.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("second esc should quit")
+	}
+}
+
+func TestEditorPaintsMaskLayer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jogger.sprite")
+	e := newEditorModel(path, &Sprite{
+		Type:    Jogger,
+		Frames:  [][]string{{"ab"}},
+		Palette: map[rune]string{'j': jerseyColor},
+	})
+
+	e = press(e,
+		tea.KeyMsg{Type: tea.KeyCtrlT}, // Switch to the mask layer
+		typed("x"),                     // Not in the palette: rejected
+		typed("j"),
+		tea.KeyMsg{Type: tea.KeyCtrlS},
+	)
+	s, err := loadSprite(path)
+	if err != nil {
+		t.Fatalf("loadSprite() error = %v", err)
+	}
+	if !reflect.DeepEqual(s.Frames, [][]string{{"ab"}}) || !reflect.DeepEqual(s.Masks, [][]string{{"j "}}) {
+		t.Errorf("saved frames %q masks %q, want art untouched and mask \"j \"", s.Frames, s.Masks)
+	}
+}
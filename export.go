@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/muesli/termenv"
+	"golang.org/x/image/font/basicfont"
+)
+
+// --- GIF and SVG export (consolerunner export) ---
+//
+// Runs the simulation headlessly like "record" and turns the styled cell
+// buffers View() draws into an animation anyone can view without a terminal.
+// GIFs are rasterised with a bundled 7x13 bitmap font; half-block and braille
+// cells are drawn as shapes so the high-resolution modes look right. SVGs keep
+// the text as text, one strip of frames slid into view by a CSS animation.
+
+const (
+	defaultExportFPS      = 10 // GIF delays are in hundredths, and fewer frames keep files small
+	defaultExportDuration = 5 * time.Second
+)
+
+// Colours for cells using the terminal default: light grey on black, as the
+// headless model assumes a dark background.
+var (
+	exportForeground = ansi16[7]
+	exportBackground = ansi16[0]
+)
+
+// exportFrame is one distinct frame of an export and when it appears.
+type exportFrame struct {
+	cells *frameBuffer
+	at    time.Duration
+}
+
+// captureFrames runs a headless model for duration at fps frames per second,
+// keeping each frame that differs from the one before. There's always at
+// least one frame.
+func captureFrames(m model, fps int, duration time.Duration) []exportFrame {
+	var frames []exportFrame
+	start := time.Now()
+	frameDelay := time.Second / time.Duration(fps)
+	for t := time.Duration(0); t == 0 || t < duration; t += frameDelay {
+		updated, _ := m.Update(tickMsg(start.Add(t)))
+		m = updated.(model)
+		cells := m.frame()
+		if n := len(frames); n > 0 && reflect.DeepEqual(frames[n-1].cells.cells, cells.cells) {
+			continue
+		}
+		frames = append(frames, exportFrame{cells: cells, at: t})
+	}
+	return frames
+}
+
+// frameEnd returns when frame i of frames gives way to the next, the last
+// one lasting until the end of the animation.
+func frameEnd(frames []exportFrame, i int, duration time.Duration) time.Duration {
+	if i+1 < len(frames) {
+		return frames[i+1].at
+	}
+	if duration > frames[i].at {
+		return duration
+	}
+	return frames[i].at + time.Second/defaultExportFPS // A single still frame
+}
+
+// cellColors resolves a cell's colours to RGB, using the export defaults for
+// the terminal's own colours and anything that doesn't parse.
+func cellColors(c cell) (fg, bg rgb) {
+	fg, bg = exportForeground, exportBackground
+	if c.Color != "" {
+		if parsed, err := parseColor(c.Color); err == nil {
+			fg = parsed
+		}
+	}
+	if c.Background != "" {
+		if parsed, err := parseColor(c.Background); err == nil {
+			bg = parsed
+		}
+	}
+	return fg, bg
+}
+
+// --- GIF ---
+
+// The bundled font sets the size of a cell in pixels.
+var (
+	gifFont       = basicfont.Face7x13
+	gifCellWidth  = gifFont.Advance
+	gifCellHeight = gifFont.Height
+)
+
+// gifPalette collects the colours used by frames, in order of first use.
+// GIFs allow 256 colours; any beyond that are drawn with the closest one.
+func gifPalette(frames []exportFrame) color.Palette {
+	seen := map[rgb]bool{}
+	var palette color.Palette
+	add := func(c rgb) {
+		if !seen[c] && len(palette) < 256 {
+			seen[c] = true
+			palette = append(palette, color.RGBA{c.R, c.G, c.B, 0xff})
+		}
+	}
+	add(exportBackground)
+	add(exportForeground)
+	for _, f := range frames {
+		for _, row := range f.cells.cells {
+			for _, c := range row {
+				fg, bg := cellColors(c)
+				add(bg)
+				add(fg)
+			}
+		}
+	}
+	return palette
+}
+
+// rasterise draws a cell buffer as a paletted image, one font cell per
+// terminal cell.
+func rasterise(b *frameBuffer, palette color.Palette) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, b.width*gifCellWidth, b.height*gifCellHeight), palette)
+	index := map[rgb]uint8{}
+	indexOf := func(c rgb) uint8 {
+		i, ok := index[c]
+		if !ok {
+			i = uint8(palette.Index(color.RGBA{c.R, c.G, c.B, 0xff}))
+			index[c] = i
+		}
+		return i
+	}
+	for y, row := range b.cells {
+		for x, c := range row {
+			if c.Char == 0 {
+				continue // Drawn with the wide glyph to its left
+			}
+			fg, bg := cellColors(c)
+			width := 1
+			for x+width < b.width && row[x+width].Char == 0 {
+				width++
+			}
+			cellRect := image.Rect(x*gifCellWidth, y*gifCellHeight, (x+width)*gifCellWidth, (y+1)*gifCellHeight)
+			fillRect(img, cellRect, indexOf(bg))
+			drawGlyph(img, cellRect, c.Char, indexOf(fg))
+		}
+	}
+	return img
+}
+
+// fillRect sets every pixel of r to palette index i.
+func fillRect(img *image.Paletted, r image.Rectangle, i uint8) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetColorIndex(x, y, i)
+		}
+	}
+}
+
+// drawGlyph draws char in r with palette index i. Block and braille glyphs
+// are drawn as shapes filling the cell; anything else comes from the font,
+// with glyphs it doesn't have drawn as '?'.
+func drawGlyph(img *image.Paletted, r image.Rectangle, char rune, i uint8) {
+	mid := r.Min.Y + r.Dy()/2
+	switch {
+	case char == ' ':
+		return
+	case char == '▀':
+		fillRect(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, mid), i)
+		return
+	case char == '▄':
+		fillRect(img, image.Rect(r.Min.X, mid, r.Max.X, r.Max.Y), i)
+		return
+	case char == '█':
+		fillRect(img, r, i)
+		return
+	case char >= 0x2800 && char <= 0x28ff:
+		dotW, dotH := r.Dx()/2, r.Dy()/4
+		for dy, row := range brailleDots {
+			for dx, bit := range row {
+				if (char-0x2800)&bit != 0 {
+					x0, y0 := r.Min.X+dx*dotW, r.Min.Y+dy*dotH
+					fillRect(img, image.Rect(x0+1, y0+1, x0+dotW, y0+dotH), i)
+				}
+			}
+		}
+		return
+	}
+
+	glyph, ok := fontGlyph(char)
+	if !ok {
+		glyph, _ = fontGlyph('?')
+	}
+	// Centre the glyph in wide cells
+	left := r.Min.X + (r.Dx()-gifCellWidth)/2
+	for y := 0; y < gifCellHeight; y++ {
+		for x := 0; x < gifCellWidth; x++ {
+			if gifFont.Mask.(*image.Alpha).AlphaAt(x, glyph+y).A != 0 {
+				img.SetColorIndex(left+x, r.Min.Y+y, i)
+			}
+		}
+	}
+}
+
+// fontGlyph returns the row of the font's mask where char's glyph starts.
+func fontGlyph(char rune) (int, bool) {
+	for _, rr := range gifFont.Ranges {
+		if char >= rr.Low && char < rr.High {
+			return (rr.Offset + int(char-rr.Low)) * gifCellHeight, true
+		}
+	}
+	return 0, false
+}
+
+// writeGIF writes frames as a looping animated GIF.
+func writeGIF(w io.Writer, frames []exportFrame, duration time.Duration) error {
+	palette := gifPalette(frames)
+	anim := &gif.GIF{}
+	shown := 0 // Hundredths of a second so far, so rounding doesn't drift
+	for i, f := range frames {
+		end := int(math.Round(frameEnd(frames, i, duration).Seconds() * 100))
+		delay := end - shown
+		if delay < 1 {
+			delay = 1
+		}
+		shown += delay
+		anim.Image = append(anim.Image, rasterise(f.cells, palette))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// --- SVG ---
+
+// SVG cell size in pixels; text is stretched to the grid with textLength, so
+// the viewer's monospace font doesn't have to match it exactly.
+const (
+	svgCellWidth  = 8.4
+	svgCellHeight = 17
+	svgFontSize   = 14
+)
+
+// writeSVG writes frames as an animated SVG. The frames sit side by side in
+// one strip, and a stepped CSS animation moves each into the view in turn.
+func writeSVG(w io.Writer, frames []exportFrame, duration time.Duration) error {
+	bw := bufio.NewWriter(w)
+	cols, rows := frames[0].cells.width, frames[0].cells.height
+	width, height := float64(cols)*svgCellWidth, float64(rows*svgCellHeight)
+	total := frameEnd(frames, len(frames)-1, duration)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n",
+		width, height, width, height)
+	fmt.Fprintln(bw, "<style>")
+	fmt.Fprintf(bw, "text{font-family:monospace;font-size:%dpx;white-space:pre;dominant-baseline:central}\n", svgFontSize)
+	if len(frames) > 1 {
+		fmt.Fprintf(bw, "#frames{animation:play %.3fs step-end infinite}\n", total.Seconds())
+		fmt.Fprintln(bw, "@keyframes play{")
+		for i, f := range frames {
+			fmt.Fprintf(bw, "%.3f%%{transform:translateX(%gpx)}\n", 100*f.at.Seconds()/total.Seconds(), float64(-i)*width)
+		}
+		fmt.Fprintln(bw, "}")
+	}
+	fmt.Fprintln(bw, "</style>")
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", exportBackground.hex())
+	fmt.Fprintln(bw, `<g id="frames">`)
+	for i, f := range frames {
+		fmt.Fprintf(bw, `<g transform="translate(%g,0)">`+"\n", float64(i)*width)
+		writeSVGCells(bw, f.cells)
+		fmt.Fprintln(bw, "</g>")
+	}
+	fmt.Fprintln(bw, "</g>")
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// writeSVGCells writes one frame: a rect for each run of background colour
+// and a text element for each run of glyphs with the same foreground.
+func writeSVGCells(w io.Writer, b *frameBuffer) {
+	for y, row := range b.cells {
+		top := float64(y * svgCellHeight)
+
+		for x := 0; x < len(row); {
+			_, bg := cellColors(row[x])
+			end := x + 1
+			for end < len(row) {
+				if _, next := cellColors(row[end]); next != bg {
+					break
+				}
+				end++
+			}
+			if bg != exportBackground {
+				fmt.Fprintf(w, `<rect x="%g" y="%g" width="%g" height="%d" fill="%s"/>`+"\n",
+					float64(x)*svgCellWidth, top, float64(end-x)*svgCellWidth, svgCellHeight, bg.hex())
+			}
+			x = end
+		}
+
+		for x := 0; x < len(row); {
+			if row[x].Char == ' ' || row[x].Char == 0 {
+				x++
+				continue
+			}
+			fg, _ := cellColors(row[x])
+			var text strings.Builder
+			end := x
+			for end < len(row) && row[end].Char != ' ' {
+				if next, _ := cellColors(row[end]); next != fg {
+					break
+				}
+				if row[end].Char != 0 {
+					text.WriteRune(row[end].Char)
+				}
+				end++
+			}
+			fmt.Fprintf(w, `<text x="%g" y="%g" textLength="%g" lengthAdjust="spacingAndGlyphs" fill="%s">%s</text>`+"\n",
+				float64(x)*svgCellWidth, top+svgCellHeight/2.0, float64(end-x)*svgCellWidth, fg.hex(), svgEscape(text.String()))
+			x = end
+		}
+	}
+}
+
+// svgEscape escapes text for an SVG element.
+func svgEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// runExport implements "consolerunner export".
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config file")
+	profile := fs.String("profile", "", "named profile from the config file to use")
+	spriteDir := fs.String("sprites", "", "sprite directory (default from config)")
+	width := fs.Int("width", defaultHeadlessWidth, "width in cells")
+	height := fs.Int("height", defaultHeadlessHeight, "height in cells")
+	seed := fs.Int64("seed", 1, "random seed; the same seed gives the same animation")
+	duration := fs.Duration("duration", defaultExportDuration, "length of the animation")
+	fps := fs.Int("fps", defaultExportFPS, "frames per second")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner export [flags] <out.gif | out.svg>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *width <= 0 || *height <= 0 || *fps <= 0 || *duration < 0 {
+		fmt.Fprintln(os.Stderr, "--width, --height and --fps must be greater than zero and --duration must not be negative")
+		return 2
+	}
+	path := fs.Arg(0)
+	var write func(io.Writer, []exportFrame, time.Duration) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gif":
+		write = writeGIF
+	case ".svg":
+		write = writeSVG
+	default:
+		fmt.Fprintf(os.Stderr, "Can't export to %q (want a .gif or .svg file)\n", path)
+		return 2
+	}
+
+	scene, err := loadHeadlessScene(*configPath, *profile, *spriteDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	// Truecolor so the sky gradient keeps every shade
+	m := newHeadlessModel(scene, termenv.TrueColor, *seed, *width, *height)
+	frames := captureFrames(m, *fps, *duration)
+
+	out, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't create export: %v\n", err)
+		return 1
+	}
+	err = write(out, frames, *duration)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't write export: %v\n", err)
+		return 1
+	}
+	return 0
+}
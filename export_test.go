@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/gif"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muesli/termenv"
+)
+
+func TestWriteGIF(t *testing.T) {
+	m := newHeadlessModel(defaultScene(), termenv.TrueColor, 3, 40, 12)
+	frames := captureFrames(m, 10, 2*time.Second)
+	var out bytes.Buffer
+	if err := writeGIF(&out, frames, 2*time.Second); err != nil {
+		t.Fatalf("writeGIF() error = %v", err)
+	}
+
+	anim, err := gif.DecodeAll(&out)
+	if err != nil {
+		t.Fatalf("output isn't a GIF: %v", err)
+	}
+	if len(anim.Image) != len(frames) {
+		t.Errorf("GIF has %d frames, want %d", len(anim.Image), len(frames))
+	}
+	if b := anim.Image[0].Bounds(); b.Dx() != 40*gifCellWidth || b.Dy() != 12*gifCellHeight {
+		t.Errorf("GIF is %dx%d pixels, want %dx%d", b.Dx(), b.Dy(), 40*gifCellWidth, 12*gifCellHeight)
+	}
+	total := 0
+	for _, d := range anim.Delay {
+		total += d
+	}
+	if total != 200 {
+		t.Errorf("GIF lasts %d/100s, want 200", total)
+	}
+}
+
+func TestRasterise(t *testing.T) {
+	b := newFrameBuffer(3, 1)
+	b.cells[0][0] = cell{Char: 'A', Color: "9"}
+	b.cells[0][1] = cell{Char: '█', Color: "12"}
+	b.cells[0][2] = cell{Char: '⣿', Color: "10", Background: "#123456"}
+	palette := gifPalette([]exportFrame{{cells: b}})
+	img := rasterise(b, palette)
+
+	count := func(x0 int, c color.Color) int {
+		n := 0
+		want := color.RGBAModel.Convert(c)
+		for y := 0; y < gifCellHeight; y++ {
+			for x := x0; x < x0+gifCellWidth; x++ {
+				if img.At(x, y) == want {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	red, blue, green := color.RGBA{255, 0, 0, 255}, color.RGBA{92, 92, 255, 255}, color.RGBA{0, 255, 0, 255}
+	if n := count(0, red); n == 0 || n == gifCellWidth*gifCellHeight {
+		t.Errorf("'A' has %d red pixels, want some but not the whole cell", n)
+	}
+	if n := count(gifCellWidth, blue); n != gifCellWidth*gifCellHeight {
+		t.Errorf("'█' has %d blue pixels, want the whole cell", n)
+	}
+	if n := count(2*gifCellWidth, green); n == 0 || count(2*gifCellWidth, color.RGBA{0x12, 0x34, 0x56, 255}) == 0 {
+		t.Errorf("'⣿' has %d green pixels, want eight dots on its background", n)
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	b := newFrameBuffer(4, 1)
+	b.drawLine(0, 0, "a<b", ' ', fixedColor("9"))
+	c := newFrameBuffer(4, 1)
+	c.drawLine(1, 0, "&", ' ', fixedColor("#00ff00"))
+	frames := []exportFrame{{cells: b}, {cells: c, at: 500 * time.Millisecond}}
+
+	var out bytes.Buffer
+	if err := writeSVG(&out, frames, time.Second); err != nil {
+		t.Fatalf("writeSVG() error = %v", err)
+	}
+	svg := out.String()
+
+	// Must be well-formed XML
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("SVG isn't well-formed: %v\n%s", err, svg)
+		}
+	}
+	for _, want := range []string{
+		"animation:play 1.000s step-end infinite",
+		"0.000%{transform:translateX(0px)}",
+		"50.000%{transform:translateX(-33.6px)}",
+		`fill="#ff0000">a&lt;b</text>`,
+		`fill="#00ff00">&amp;</text>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG is missing %q:\n%s", want, svg)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Game mode (--game) ---
+//
+// An endless runner: the player's runner stays near the left edge while
+// obstacles scroll in from the right, and the player jumps over them or
+// ducks under them. Hitting one ends the run. The score is the distance run
+// plus a bonus for every obstacle cleared, so running faster scores faster
+// but leaves less time to react.
+//
+// Games are always drawn in text mode, whatever --render says: the player's
+// and the obstacles' art is a glyph to a cell, and jumps, speeds and
+// overhead obstacles are all in cells to match it. (In the high-resolution
+// modes art is a glyph to a pixel, so heights measured in cells wouldn't
+// line up with it.)
+
+const (
+	gameStartSpeed  = 1.0 // Cells per tick
+	gameMinSpeed    = 0.5
+	gameMaxSpeed    = 3.0
+	gameSpeedStep   = 0.25  // Change per faster/slower key press
+	gameJumpSpeed   = 1.0   // Rows per tick upwards at take-off
+	gameGravity     = 0.125 // Rows per tick per tick
+	gameDuckTicks   = 8     // A duck lasts this long (terminals don't report key releases)
+	gamePlayerCol   = 4     // Column the player runs at
+	gameClearBonus  = 10    // Points for each obstacle cleared
+	gameMinGap      = 14    // Cells between obstacles at the starting speed, at least...
+	gameMaxGap      = 36    // ...and at most; jumps cover more ground at speed, so gaps grow with it
+	gameGroundGlyph = '='
+	gameGroundColor = "240"
+)
+
+// obstacleKind is a kind of obstacle, looked up in obstacleKinds.
+type obstacleKind int
+
+const (
+	obstacleHurdle obstacleKind = iota
+	obstaclePuddle
+	obstacleRock
+	obstacleBranch
+)
+
+// obstacleStyle describes how a kind of obstacle looks and where it is.
+type obstacleStyle struct {
+	art      []string
+	color    string
+	overhead int // Rows between the ground and the bottom of the art; duck under these
+}
+
+var obstacleKinds = map[obstacleKind]obstacleStyle{
+	obstacleHurdle: {art: []string{"T--T", "|  |"}, color: "255"},
+	obstaclePuddle: {art: []string{"~~~~~"}, color: "39"},
+	obstacleRock:   {art: []string{" _ ", "(_)"}, color: "245"},
+	obstacleBranch: {art: []string{"\\_/\\__"}, color: "130", overhead: 2},
+}
+
+// gameDuckArt replaces the player's art while ducking: a row shorter than
+// the medium art, so overhead obstacles pass over it.
+var gameDuckArt = [][]string{{"_o_", "/ \\"}}
+
+// obstacle is one obstacle on the track.
+type obstacle struct {
+	kind  obstacleKind
+	x     float64 // Left edge, in cells
+	prevX float64 // x at the previous tick, for interpolation
+	clear bool    // The player has got past it
+}
+
+// game is the state of a game in progress.
+type game struct {
+	obstacles []obstacle
+	speed     float64 // Cells per tick
+	distance  float64 // Cells run
+	cleared   int     // Obstacles got past
+	nextAt    float64 // Distance at which the next obstacle appears
+	air       float64 // Height of the player's feet above the ground, in rows
+	duck      int     // Ticks of ducking left
+	ducked    bool    // The player is drawn with gameDuckArt
+	run       *Sprite // The player's art when not ducking
+	over      bool    // The player hit something
+}
+
+// score is the points scored so far.
+func (g *game) score() int {
+	return int(g.distance) + gameClearBonus*g.cleared
+}
+
+// startGame replaces the crowd with a single player-controlled runner and
+// starts a new game.
+func (m *model) startGame() {
+	m.setRenderMode(renderText)
+	run := getSpriteVariant(Jogger, sizeMedium)
+	player := Runner{Type: Jogger, Color: m.colors().jersey(m.rng)}
+	if len(m.runners) > 0 {
+		player.Color = m.runners[0].Color // Keep the jersey across restarts
+	}
+	useSprite(&player, run)
+	m.runners = []Runner{player}
+	m.game = &game{speed: gameStartSpeed, run: run, nextAt: gameMinGap}
+	m.selected = noSelection
+	m.placePlayer()
+	m.runners[0].PrevPos = m.runners[0].Pos
+}
+
+// groundY is the top of the ground row, where the player's feet are.
+func (m model) groundY() float64 {
+	_, height := m.fieldSize()
+	return float64(height - 1)
+}
+
+// placePlayer puts the player at its column, standing on the ground or as
+// high as it has jumped.
+func (m *model) placePlayer() {
+	p := &m.runners[0]
+	p.Pos.X = gamePlayerCol
+	p.Pos.Y = m.groundY() - float64(len(p.ArtFrames[p.CurrentFrameIdx])) - m.game.air
+}
+
+// stepGame advances the game by one tick.
+func (m *model) stepGame() {
+	g := m.game
+	if g.over {
+		return
+	}
+	width, _ := m.fieldSize()
+	p := &m.runners[0]
+	p.PrevPos = p.Pos
+
+	// Gravity while in the air; VelocityY is downwards, like the screen
+	if g.air > 0 || p.VelocityY < 0 {
+		g.air -= p.VelocityY
+		p.VelocityY += gameGravity
+		if g.air <= 0 {
+			g.air, p.VelocityY = 0, 0
+		}
+	}
+	if g.duck > 0 {
+		g.duck--
+	}
+	if duck := g.duck > 0 && g.air == 0; duck != g.ducked {
+		g.ducked = duck
+		if duck {
+			useSprite(p, &Sprite{Type: Jogger, Frames: gameDuckArt})
+		} else {
+			useSprite(p, g.run)
+		}
+	} else if g.air == 0 {
+		nextFrame(p) // Legs only move on the ground
+	}
+	m.placePlayer()
+
+	// Scroll the obstacles, dropping those that have left on the left
+	g.distance += g.speed
+	kept := g.obstacles[:0]
+	for _, o := range g.obstacles {
+		o.prevX = o.x
+		o.x -= g.speed
+		if !o.clear && o.bounds(m).Max.X <= runnerBounds(p, p.Pos).Min.X {
+			o.clear = true
+			g.cleared++
+		}
+		if o.bounds(m).Max.X > 0 {
+			kept = append(kept, o)
+		}
+	}
+	g.obstacles = kept
+
+	if g.distance >= g.nextAt {
+		kind := obstacleKind(m.rng.Intn(len(obstacleKinds)))
+		g.obstacles = append(g.obstacles, obstacle{kind: kind, x: float64(width), prevX: float64(width)})
+		gap := float64(gameMinGap + m.rng.Intn(gameMaxGap-gameMinGap+1))
+		g.nextAt = g.distance + gap*g.speed/gameStartSpeed
+	}
+
+	player := runnerBounds(p, p.Pos)
+	for _, o := range g.obstacles {
+		if o.bounds(m).Overlaps(player) {
+			g.over = true
+			m.results.addGame(gameScore{Score: g.score(), Distance: int(g.distance), Cleared: g.cleared, Date: time.Now()})
+			return
+		}
+	}
+}
+
+// bounds is the box an obstacle covers, in cells.
+func (o obstacle) bounds(m *model) image.Rectangle {
+	style := obstacleKinds[o.kind]
+	bottom := int(m.groundY()) - style.overhead
+	x := int(math.Floor(o.x))
+	return image.Rect(x, bottom-len(style.art), x+lipgloss.Width(style.art[0]), bottom)
+}
+
+// handleGameKey applies a key press to the game. Once it's over, only the
+// restart key does anything, so a late jump doesn't skip the final score.
+func (m *model) handleGameKey(msg tea.KeyMsg) {
+	g := m.game
+	switch {
+	case g.over:
+		if key.Matches(msg, m.keys.Restart) {
+			m.startGame()
+		}
+	case key.Matches(msg, m.keys.Jump):
+		if g.air == 0 {
+			m.runners[0].VelocityY = -gameJumpSpeed
+			g.duck = 0
+		}
+	case key.Matches(msg, m.keys.Duck):
+		if g.air == 0 {
+			g.duck = gameDuckTicks
+		}
+	case key.Matches(msg, m.keys.Faster):
+		g.speed = math.Min(g.speed+gameSpeedStep, gameMaxSpeed)
+	case key.Matches(msg, m.keys.Slower):
+		g.speed = math.Max(g.speed-gameSpeedStep, gameMinSpeed)
+	}
+}
+
+// drawGame draws the ground and the obstacles.
+func (m model) drawGame(b *frameBuffer) {
+	ground := int(m.groundY())
+	for x := 0; x < b.width; x++ {
+		b.set(x, ground, gameGroundGlyph, gameGroundColor)
+	}
+	alpha := m.interpolationAlpha()
+	for _, o := range m.game.obstacles {
+		style := obstacleKinds[o.kind]
+		box := o.bounds(&m)
+		x := int(math.Floor(o.prevX + (o.x-o.prevX)*alpha))
+		for i, line := range style.art {
+			b.drawLine(x, box.Min.Y+i, line, ' ', fixedColor(style.color))
+		}
+	}
+}
+
+// drawGameOverlay draws the score along the top and, once the run is over,
+// how to start another.
+func (m model) drawGameOverlay(b *frameBuffer) {
+	g := m.game
+	hud := fmt.Sprintf("Score %d  Distance %d  Speed %.2f", g.score(), int(g.distance), g.speed)
+	b.drawLine(0, 0, hud, 0, fixedColor(m.colors().Sun))
+	if !g.over {
+		return
+	}
+	lines := []string{
+		"GAME OVER",
+		fmt.Sprintf("Score %d", g.score()),
+		fmt.Sprintf("%s to run again, %s to quit", m.keys.Restart.Help().Key, m.keys.Quit.Help().Key),
+	}
+	top := (b.height - len(lines)) / 2
+	for i, line := range lines {
+		b.drawLine((b.width-lipgloss.Width(line))/2, top+i, line, 0, fixedColor("9"))
+	}
+}
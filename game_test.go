@@ -0,0 +1,181 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// gameModel is a 40x12 game with no obstacles coming unless a test adds them.
+func gameModel() model {
+	return gameModelIn(renderText)
+}
+
+// gameModelIn is gameModel with the scene set to render in mode r.
+func gameModelIn(r renderMode) model {
+	scene := defaultScene()
+	scene.Render = r
+	m := seededModel(scene, display{}, 1)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 12})
+	m = updated.(model)
+	m.startGame()
+	m.game.nextAt = 1e9
+	return m
+}
+
+// gameKey sends a key press to m.
+func gameKey(m model, k tea.KeyType) model {
+	updated, _ := m.Update(tea.KeyMsg{Type: k})
+	return updated.(model)
+}
+
+// addObstacle puts an obstacle of the given kind gap cells ahead of the
+// player.
+func addObstacle(m *model, kind obstacleKind, gap int) {
+	p := &m.runners[0]
+	x := float64(runnerBounds(p, p.Pos).Max.X + gap)
+	m.game.obstacles = append(m.game.obstacles, obstacle{kind: kind, x: x, prevX: x})
+}
+
+func TestGameCollisionEndsRun(t *testing.T) {
+	m := gameModel()
+	addObstacle(&m, obstacleHurdle, 3)
+	for i := 0; i < 10 && !m.game.over; i++ {
+		m.step()
+	}
+	if !m.game.over {
+		t.Fatal("ran through a hurdle without jumping")
+	}
+	if len(m.results.Games) != 1 || m.results.Games[0].Score != m.game.score() {
+		t.Errorf("recorded %+v, want the game's score", m.results.Games)
+	}
+
+	// Once over, nothing moves and only restart does anything
+	distance := m.game.distance
+	m = gameKey(m, tea.KeySpace)
+	m.step()
+	if m.game.distance != distance || m.game.air != 0 {
+		t.Errorf("game went on after it was over")
+	}
+	if rows := plainRows(m.frame()); !strings.Contains(strings.Join(rows, "\n"), "GAME OVER") {
+		t.Errorf("no GAME OVER shown:\n%s", strings.Join(rows, "\n"))
+	}
+
+	m = gameKey(m, tea.KeyEnter)
+	if m.game.over || m.game.distance != 0 || len(m.game.obstacles) != 0 {
+		t.Errorf("restart left %+v, want a new game", *m.game)
+	}
+}
+
+func TestGameJumpClearsHurdle(t *testing.T) {
+	m := gameModel()
+	addObstacle(&m, obstacleHurdle, 3)
+	m = gameKey(m, tea.KeySpace)
+	for i := 0; i < 30; i++ {
+		m.step()
+		if m.game.over {
+			t.Fatalf("hit the hurdle at tick %d, %v rows up", i, m.game.air)
+		}
+	}
+	if m.game.cleared != 1 || m.game.air != 0 {
+		t.Errorf("cleared %d obstacles and landed at %v, want 1 cleared and back on the ground", m.game.cleared, m.game.air)
+	}
+	if want := 30 + gameClearBonus; m.game.score() != want {
+		t.Errorf("score = %d, want %d", m.game.score(), want)
+	}
+	if rows := plainRows(m.frame()); !strings.HasPrefix(rows[0], "Score 40") {
+		t.Errorf("top row = %q, want the score", rows[0])
+	}
+}
+
+func TestGameDuckUnderBranch(t *testing.T) {
+	m := gameModel()
+	addObstacle(&m, obstacleBranch, 2)
+	for i := 0; i < 12 && !m.game.over; i++ {
+		m.step()
+	}
+	if !m.game.over {
+		t.Fatal("ran into a branch standing up without hitting it")
+	}
+
+	m = gameModel()
+	addObstacle(&m, obstacleBranch, 2)
+	for i := 0; i < 20; i++ {
+		m = gameKey(m, tea.KeyDown) // Key repeat keeps the player down
+		m.step()
+		if m.game.over {
+			t.Fatalf("hit the branch while ducking at tick %d", i)
+		}
+	}
+	if m.game.cleared != 1 {
+		t.Errorf("cleared %d obstacles, want 1", m.game.cleared)
+	}
+}
+
+func TestGameInEveryRenderMode(t *testing.T) {
+	for _, r := range []renderMode{renderText, renderHalfBlock, renderBraille} {
+		for _, kind := range []obstacleKind{obstacleHurdle, obstacleBranch} {
+			m := gameModelIn(r)
+			if m.render != renderText {
+				t.Errorf("--render %s: game drawn in %s, want text", r, m.render)
+			}
+			addObstacle(&m, kind, 2)
+			for i := 0; i < 12 && !m.game.over; i++ {
+				m.step()
+			}
+			if !m.game.over {
+				t.Errorf("--render %s: ran into obstacle %d standing up and cleared %d", r, kind, m.game.cleared)
+			}
+		}
+
+		// Nor does a config reload switch it
+		m := gameModelIn(renderText)
+		scene := defaultScene()
+		scene.Render = r
+		m.applyScene(scene)
+		if m.render != renderText {
+			t.Errorf("reloading with render = %s switched the game to %s", r, m.render)
+		}
+	}
+}
+
+func TestGameReloadKeepsPlayerArt(t *testing.T) {
+	m := gameModel()
+	m = gameKey(m, tea.KeyDown)
+	m.step()
+	m.reloadArt()
+	p := m.runners[0]
+	if !m.game.ducked || !reflect.DeepEqual(p.ArtFrames, gameDuckArt) {
+		t.Errorf("reloading while ducking gave art %q, want the duck art", p.ArtFrames)
+	}
+
+	m = gameModel()
+	m.reloadArt()
+	p = m.runners[0]
+	bottom := p.Pos.Y + float64(len(p.ArtFrames[p.CurrentFrameIdx]))
+	if p.Size != sizeMedium || bottom != m.groundY() {
+		t.Errorf("reloading gave %s art with its feet at %v, want medium art on the ground at %v", p.Size, bottom, m.groundY())
+	}
+}
+
+func TestGameSpeedKeys(t *testing.T) {
+	m := gameModel()
+	m = gameKey(m, tea.KeyRight)
+	if m.game.speed != gameStartSpeed+gameSpeedStep {
+		t.Errorf("speed = %v after one faster, want %v", m.game.speed, gameStartSpeed+gameSpeedStep)
+	}
+	for i := 0; i < 20; i++ {
+		m = gameKey(m, tea.KeyRight)
+	}
+	if m.game.speed != gameMaxSpeed {
+		t.Errorf("speed = %v, want the maximum %v", m.game.speed, gameMaxSpeed)
+	}
+	for i := 0; i < 20; i++ {
+		m = gameKey(m, tea.KeyLeft)
+	}
+	if m.game.speed != gameMinSpeed {
+		t.Errorf("speed = %v, want the minimum %v", m.game.speed, gameMinSpeed)
+	}
+}
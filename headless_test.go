@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/muesli/termenv"
+)
+
+// headlessOutput renders ticks frames of the default scene to a string.
+func headlessOutput(t *testing.T, profile termenv.Profile, seed int64, ticks int) string {
+	t.Helper()
+	var out bytes.Buffer
+	m := newHeadlessModel(defaultScene(), profile, seed, 40, 12)
+	if err := writeHeadlessFrames(m, ticks, &out, "", ""); err != nil {
+		t.Fatalf("writeHeadlessFrames() error = %v", err)
+	}
+	return out.String()
+}
+
+func TestHeadlessIsReproducible(t *testing.T) {
+	first := headlessOutput(t, termenv.Ascii, 7, 5)
+	if again := headlessOutput(t, termenv.Ascii, 7, 5); again != first {
+		t.Errorf("the same seed gave different frames")
+	}
+	if other := headlessOutput(t, termenv.Ascii, 8, 5); other == first {
+		t.Errorf("different seeds gave the same frames")
+	}
+
+	frames := strings.Split(first, headlessFrameSeparator)
+	if len(frames) != 5 {
+		t.Fatalf("got %d frames, want 5", len(frames))
+	}
+	for i, f := range frames {
+		if lines := strings.Count(f, "\n"); lines != 12 {
+			t.Errorf("frame %d has %d lines, want 12", i, lines)
+		}
+	}
+	if frames[0] == frames[1] {
+		t.Errorf("frames 0 and 1 are the same, want the scene to move")
+	}
+	if strings.Contains(first, "\x1b") {
+		t.Errorf("plain output contains escape sequences")
+	}
+	if colored := headlessOutput(t, termenv.TrueColor, 7, 5); !strings.Contains(colored, "\x1b[") {
+		t.Errorf("coloured output has no escape sequences")
+	}
+}
+
+func TestHeadlessFramesShowEachTick(t *testing.T) {
+	m := newHeadlessModel(defaultScene(), termenv.Ascii, 3, 40, 12)
+	m.background = nil
+	r := *newTestRunner(5, 4, 1, [][]string{{"@"}})
+	r.PrevPos = r.Pos
+	m.runners = []Runner{r}
+
+	var cols []int
+	headlessFrames(m, 4, func(i int, frame string) error {
+		cols = append(cols, strings.Index(strings.Split(frame, "\n")[4], "@"))
+		return nil
+	})
+	for i := 1; i < len(cols); i++ {
+		if cols[i] != cols[i-1]+1 {
+			t.Errorf("runner at columns %v in successive frames, want it a column further on each tick", cols)
+			break
+		}
+	}
+}
+
+func TestHeadlessFramesToDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "frames")
+	m := newHeadlessModel(defaultScene(), termenv.Ascii, 1, 20, 6)
+	if err := writeHeadlessFrames(m, 3, nil, dir, ".txt"); err != nil {
+		t.Fatalf("writeHeadlessFrames() error = %v", err)
+	}
+	for _, name := range []string{"frame-0000.txt", "frame-0001.txt", "frame-0002.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+}
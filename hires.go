@@ -0,0 +1,137 @@
+package main
+
+// --- High-resolution rendering (--render halfblock|braille) ---
+//
+// Runner positions are float64, but in text mode a runner can only be drawn on
+// whole cells, so slow runners visibly step from cell to cell. The
+// high-resolution modes split each cell into pixels: two stacked pixels drawn
+// with the half-block glyphs ▀ and ▄ (which can show two colours per cell), or
+// a 2x4 braille pattern (one colour per cell). The scene is drawn into a pixel
+// buffer with one sprite glyph per pixel, which makes sprites pixel art, and
+// the simulation runs in pixels too, so runners move by fractions of a cell.
+
+// Braille dot bits for the pixel at (x, y) within a cell, per the Unicode
+// braille pattern block (U+2800).
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// cellPixels returns how many pixels wide and tall a terminal cell is.
+func (r renderMode) cellPixels() (x, y int) {
+	switch r {
+	case renderHalfBlock:
+		return 1, 2
+	case renderBraille:
+		return 2, 4
+	}
+	return 1, 1
+}
+
+// fieldSize returns the size of the area runners move in, in the units they
+// are drawn in: cells in text mode, pixels otherwise.
+func (m model) fieldSize() (width, height int) {
+	px, py := m.render.cellPixels()
+	return m.termWidth * px, m.termHeight * py
+}
+
+// setRenderMode switches render modes, rescaling runner positions and speeds
+// so the scene looks the same in the new units.
+func (m *model) setRenderMode(r renderMode) {
+	// Half blocks and braille are Unicode, and games are played in cells
+	// (see game.go)
+	if r == "" || m.display.ascii || m.game != nil {
+		r = renderText
+	}
+	if m.render == "" || m.render == r {
+		m.render = r
+		return
+	}
+	oldX, oldY := m.render.cellPixels()
+	newX, newY := r.cellPixels()
+	sx, sy := float64(newX)/float64(oldX), float64(newY)/float64(oldY)
+	for i := range m.runners {
+		runner := &m.runners[i]
+		runner.Pos = Position{X: runner.Pos.X * sx, Y: runner.Pos.Y * sy}
+		runner.PrevPos = Position{X: runner.PrevPos.X * sx, Y: runner.PrevPos.Y * sy}
+		runner.VelocityX *= sx
+		runner.VelocityY *= sy
+	}
+	m.render = r
+}
+
+// packPixels converts a buffer drawn at the mode's pixel resolution into
+// terminal cells. A pixel is lit if anything was drawn on it.
+func packPixels(pixels *frameBuffer, r renderMode) *frameBuffer {
+	px, py := r.cellPixels()
+	if px == 1 && py == 1 {
+		return pixels
+	}
+	b := newFrameBuffer(pixels.width/px, pixels.height/py)
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			if r == renderHalfBlock {
+				top, topLit := pixels.pixel(x, 2*y)
+				bottom, bottomLit := pixels.pixel(x, 2*y+1)
+				b.cells[y][x] = halfBlockCell(top, topLit, bottom, bottomLit)
+			} else {
+				b.cells[y][x] = brailleCell(pixels, x*px, y*py)
+			}
+		}
+	}
+	return b
+}
+
+// pixel returns the cell at (x, y) and whether anything is drawn there.
+func (b *frameBuffer) pixel(x, y int) (cell, bool) {
+	c := b.cells[y][x]
+	return c, c.Char != ' '
+}
+
+// halfBlockCell packs a top and bottom pixel into one cell. When both are lit
+// the bottom pixel's colour becomes the cell background, which can't be the
+// terminal's default foreground colour, so the glyph is flipped to put the
+// uncoloured pixel in the foreground.
+func halfBlockCell(top cell, topLit bool, bottom cell, bottomLit bool) cell {
+	switch {
+	case topLit && bottomLit && top.Color == bottom.Color:
+		return cell{Char: '█', Color: top.Color}
+	case topLit && bottomLit && top.Color == "":
+		return cell{Char: '▀', Background: bottom.Color}
+	case topLit && bottomLit:
+		return cell{Char: '▄', Color: bottom.Color, Background: top.Color}
+	case topLit:
+		return cell{Char: '▀', Color: top.Color}
+	case bottomLit:
+		return cell{Char: '▄', Color: bottom.Color}
+	}
+	return cell{Char: ' '}
+}
+
+// brailleCell packs the 2x4 pixels with their top-left corner at (x, y) into a
+// braille pattern. A cell has one colour, so the most common colour among the
+// lit dots wins (ties go to the first one found, top to bottom).
+func brailleCell(pixels *frameBuffer, x, y int) cell {
+	var dots rune
+	counts := make(map[string]int)
+	color, best := "", 0
+	for dy := 0; dy < 4; dy++ {
+		for dx := 0; dx < 2; dx++ {
+			c, lit := pixels.pixel(x+dx, y+dy)
+			if !lit {
+				continue
+			}
+			dots |= brailleDots[dy][dx]
+			counts[c.Color]++
+			if counts[c.Color] > best {
+				color, best = c.Color, counts[c.Color]
+			}
+		}
+	}
+	if dots == 0 {
+		return cell{Char: ' '}
+	}
+	return cell{Char: 0x2800 + dots, Color: color}
+}
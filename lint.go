@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Sprite linter (consolerunner lint-sprites [dir]) ---
+//
+// Checks sprite files for problems that render badly: frames of different
+// heights, ragged line widths (updatePosition wraps runners using the width of
+// the first line, as measured by lipgloss.Width), wide or zero-width runes
+// that break the cell grid, tabs, and trailing whitespace that pads a line
+// past the sprite's width.
+
+// spriteDiagnostic is a single lint finding. Frame and Line are 1-based
+// positions within the sprite (Line counts from the top of the frame); both
+// are zero for problems with the file as a whole.
+type spriteDiagnostic struct {
+	File  string
+	Frame int
+	Line  int
+	Msg   string
+}
+
+func (d spriteDiagnostic) String() string {
+	if d.Frame == 0 {
+		return fmt.Sprintf("%s: %s", d.File, d.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Frame, d.Line, d.Msg)
+}
+
+// lintSprite checks a parsed sprite and returns its diagnostics in file order.
+func lintSprite(path string, s *Sprite) []spriteDiagnostic {
+	var diags []spriteDiagnostic
+	report := func(frame, line int, format string, args ...interface{}) {
+		diags = append(diags, spriteDiagnostic{File: path, Frame: frame, Line: line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	wantHeight := len(s.Frames[0])
+	wantWidth := commonWidth(s.Frames)
+
+	for f, frame := range s.Frames {
+		if len(frame) != wantHeight {
+			report(f+1, 1, "frame is %d lines tall, frame 1 is %d", len(frame), wantHeight)
+		}
+		for l, line := range frame {
+			col, tabs := 0, false
+			for _, r := range line {
+				col++
+				switch w := lipgloss.Width(string(r)); {
+				case r == '\t':
+					report(f+1, l+1, "column %d: tab character (use spaces)", col)
+					tabs = true
+				case w == 0:
+					report(f+1, l+1, "column %d: zero-width rune %U", col, r)
+				case w > 1:
+					report(f+1, l+1, "column %d: wide rune %q takes %d cells", col, r, w)
+				}
+			}
+
+			if tabs {
+				continue // How wide a tab is depends on the terminal; fix the tab first
+			}
+			width := lipgloss.Width(line)
+			trimmed := strings.TrimRightFunc(line, unicode.IsSpace)
+			switch {
+			case width == wantWidth:
+				// Fine
+			case width > wantWidth && lipgloss.Width(trimmed) <= wantWidth:
+				report(f+1, l+1, "trailing whitespace pads line to %d cells, sprite is %d wide", width, wantWidth)
+			case width > wantWidth:
+				report(f+1, l+1, "line is %d cells wide, sprite is %d wide", width, wantWidth)
+			default:
+				report(f+1, l+1, "line is %d cells wide, sprite is %d wide (pad with trailing spaces)", width, wantWidth)
+			}
+		}
+	}
+	return diags
+}
+
+// commonWidth returns the most common line width across all frames, which is
+// taken as the sprite's intended width. Ties go to the wider width.
+func commonWidth(frames [][]string) int {
+	counts := make(map[int]int)
+	for _, frame := range frames {
+		for _, line := range frame {
+			counts[lipgloss.Width(line)]++
+		}
+	}
+	best, bestCount := 0, 0
+	for width, count := range counts {
+		if count > bestCount || (count == bestCount && width > best) {
+			best, bestCount = width, count
+		}
+	}
+	return best
+}
+
+// runLintSprites implements "consolerunner lint-sprites [dir]". It returns 1
+// if any sprite has problems, so it can gate CI.
+func runLintSprites(args []string) int {
+	fs := flag.NewFlagSet("lint-sprites", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config file (for sprite_dir)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner lint-sprites [flags] [dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	dir := fs.Arg(0)
+	if dir == "" {
+		scene, err := loadScene(*configPath, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+			return 2
+		}
+		dir = scene.SpriteDir
+	}
+
+	files, err := spriteFiles(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read sprite directory: %v\n", err)
+		return 2
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No %s files in %s\n", spriteExt, dir)
+		return 0
+	}
+
+	problems := 0
+	for _, path := range files {
+		s, err := loadSprite(path)
+		if err != nil {
+			fmt.Println(err) // Parse errors already carry file:line
+			problems++
+			continue
+		}
+		for _, d := range lintSprite(path, s) {
+			fmt.Println(d)
+			problems++
+		}
+	}
+	if problems > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) in %d sprite file(s)\n", problems, len(files))
+		return 1
+	}
+	return 0
+}
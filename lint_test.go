@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintSpriteClean(t *testing.T) {
+	s := &Sprite{Type: Jogger, Frames: [][]string{{" o ", "/|\\"}, {" o ", "\\|/"}}}
+	if diags := lintSprite("ok.sprite", s); len(diags) != 0 {
+		t.Errorf("lintSprite() = %v, want no diagnostics", diags)
+	}
+}
+
+func TestLintSpriteProblems(t *testing.T) {
+	s := &Sprite{Type: Jogger, Frames: [][]string{
+		{
+			" o ",
+			"/|\\",
+			"/ \\",
+		},
+		{
+			" o ",
+			"/|\\  ", // Trailing whitespace past the sprite width
+			"/\t\\",  // Tab
+		},
+		{
+			" o",       // Ragged: one cell short
+			"/界",       // Wide rune
+			"a\u200bb", // Zero-width space, and too narrow
+			"/ \\",     // Extra line
+		},
+	}}
+
+	var got []string
+	for _, d := range lintSprite("x.sprite", s) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"x.sprite:2:2: trailing whitespace pads line to 5 cells, sprite is 3 wide",
+		"x.sprite:2:3: column 2: tab character (use spaces)",
+		"x.sprite:3:1: frame is 4 lines tall, frame 1 is 3",
+		"x.sprite:3:1: line is 2 cells wide, sprite is 3 wide (pad with trailing spaces)",
+		"x.sprite:3:2: column 2: wide rune '界' takes 2 cells",
+		"x.sprite:3:3: column 2: zero-width rune U+200B",
+		"x.sprite:3:3: line is 2 cells wide, sprite is 3 wide (pad with trailing spaces)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lintSprite() diagnostics:\n%s\nwant:\n%s", indentLines(got), indentLines(want))
+	}
+}
+
+func indentLines(ss []string) string {
+	out := ""
+	for _, s := range ss {
+		out += "  " + s + "\n"
+	}
+	return out
+}
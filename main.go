@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -8,13 +9,210 @@ import (
 )
 
 func main() {
-	// Create the initial model
-	m := initialModel()
+	// Subcommands have their own flags; anything else runs the animation
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "edit":
+			os.Exit(runEdit(os.Args[2:]))
+		case "lint-sprites":
+			os.Exit(runLintSprites(os.Args[2:]))
+		case "statusline":
+			os.Exit(runStatusline(os.Args[2:]))
+		case "headless":
+			os.Exit(runHeadless(os.Args[2:]))
+		case "record":
+			os.Exit(runRecord(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "scores":
+			os.Exit(runScores(os.Args[2:]))
+		case "host":
+			os.Exit(runHost(os.Args[2:]))
+		case "join":
+			os.Exit(runJoin(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		}
+	}
+
+	configPath := flag.String("config", "", "path to config file (default ~/.config/consolerunner/config.toml)")
+	profile := flag.String("profile", "", "named profile from the config file to use")
+	fps := flag.Int("fps", defaultFPS, "render frames per second (simulation speed is unaffected)")
+	spriteDir := flag.String("sprites", "", "directory of .sprite files overriding the built-in art (default ~/.config/consolerunner/sprites)")
+	watch := flag.Bool("watch", true, "reload sprites and config automatically when they change")
+	renderName := flag.String("render", "", "render mode: text, halfblock or braille (default text)")
+	colorThemeFlag := flag.String("color-theme", "", "colour theme: classic, solarized, neon or mono (default classic)")
+	lanes := flag.Int("lanes", 1, "size runner art to fit this many lanes stacked up the screen")
+	colorFlag := flag.String("color", string(colorAuto), "use colour: auto (honours NO_COLOR), never or always")
+	charsetFlag := flag.String("charset", string(charsetAuto), "glyphs the terminal can show: auto, unicode or ascii")
+	screensaver := flag.Bool("screensaver", false, "quit on any key or mouse movement and keep the scene changing")
+	idle := flag.Duration("idle", 0, "with --screensaver inside tmux: install as tmux's lock command after this much idle time, e.g. 5m")
+	inline := flag.Bool("inline", false, "draw a strip in the normal scrollback instead of taking over the screen")
+	height := flag.Int("height", 8, "with --inline: rows the strip takes")
+	duration := flag.Duration("duration", 0, "stop after this long, e.g. 10s (default: run until quit)")
+	laps := flag.Int("laps", 0, "stop once a runner has run this many laps (default: run until quit)")
+	clear := flag.Bool("clear", false, "erase the scene when --duration or --laps stops it, instead of leaving the last frame")
+	recordPath := flag.String("record", "", "record the animation to this asciicast v2 file")
+	gameMode := flag.Bool("game", false, "play: jump (space) and duck (down) past obstacles, left/right for speed")
+	httpAddr := flag.String("http", "", "serve the scene to browsers on this address instead, e.g. :8080 (see serve --http)")
+	flag.Parse()
+
+	if *httpAddr != "" {
+		os.Exit(runServe([]string{"--http", *httpAddr, "--config", *configPath, "--profile", *profile}))
+	}
+
+	if *height <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid --height %d: must be greater than zero\n", *height)
+		os.Exit(2)
+	}
+	if *duration < 0 || *laps < 0 {
+		fmt.Fprintln(os.Stderr, "--duration and --laps must not be negative")
+		os.Exit(2)
+	}
+	if *gameMode && *screensaver {
+		fmt.Fprintln(os.Stderr, "--game and --screensaver can't be used together")
+		os.Exit(2)
+	}
+
+	if *idle > 0 {
+		if !*screensaver {
+			fmt.Fprintln(os.Stderr, "--idle needs --screensaver")
+			os.Exit(2)
+		}
+		if err := installTmuxLock(*idle); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't install screensaver: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("tmux will start the screensaver after %v without input\n", *idle)
+		return
+	}
+
+	color, err := parseColorMode(*colorFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --color: %v\n", err)
+		os.Exit(2)
+	}
+	charset, err := parseCharsetMode(*charsetFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --charset: %v\n", err)
+		os.Exit(2)
+	}
+
+	var render renderMode
+	if *renderName != "" {
+		if err := render.UnmarshalText([]byte(*renderName)); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --render: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var colorTheme colorThemeName
+	if *colorThemeFlag != "" {
+		if err := colorTheme.UnmarshalText([]byte(*colorThemeFlag)); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --color-theme: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	scene, err := loadScene(*configPath, *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Flags given explicitly on the command line win over the config file,
+	// including when it is reloaded later
+	applyFlags := func(scene *Scene) {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "fps":
+				scene.FPS = *fps
+			case "sprites":
+				scene.SpriteDir = *spriteDir
+			case "render":
+				if render != "" {
+					scene.Render = render
+				}
+			case "color-theme":
+				if colorTheme != "" {
+					scene.ColorTheme = colorTheme
+				}
+			case "lanes":
+				scene.Lanes = *lanes
+			}
+		})
+	}
+	applyFlags(&scene)
+	if scene.FPS <= 0 || scene.FPS > maxFPS {
+		fmt.Fprintf(os.Stderr, "Invalid --fps %d: must be between 1 and %d\n", scene.FPS, maxFPS)
+		os.Exit(2)
+	}
+	if scene.Lanes <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid --lanes %d: must be at least 1\n", scene.Lanes)
+		os.Exit(2)
+	}
+
+	art, err := loadSpriteDir(scene.SpriteDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Sprite error: %v\n", err)
+		os.Exit(2)
+	}
+	setLoadedArt(art)
+
+	// Create the initial model for what the terminal can show
+	m := initialModel(scene, detectDisplay(color, charset))
+	m.runFor, m.laps, m.clearOnExit = *duration, *laps, *clear
+	var opts []tea.ProgramOption
+	if *inline {
+		m.height = *height
+	} else {
+		opts = append(opts, tea.WithAltScreen()) // Use AltScreen for cleaner exit
+	}
+	switch {
+	case *gameMode:
+		m.startGame()
+	case *screensaver:
+		m.startScreensaver()
+		opts = append(opts, tea.WithMouseAllMotion()) // So moving the mouse quits
+	case !*inline:
+		// Clicks, drags and the wheel; inline strips leave the mouse to the
+		// terminal, as their rows don't line up with the screen's
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+
+	var castFile *os.File
+	if *recordPath != "" {
+		if castFile, err = os.Create(*recordPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't create recording: %v\n", err)
+			os.Exit(1)
+		}
+		m.recorder = newCastRecorder(castFile)
+	}
 
 	// Create and run the Bubble Tea program
-	p := tea.NewProgram(m, tea.WithAltScreen()) // Use AltScreen for cleaner exit
-	if _, err := p.Run(); err != nil {
+	p := tea.NewProgram(m, opts...)
+
+	if *watch {
+		stop := make(chan struct{})
+		defer close(stop)
+		w := newWatcher(*configPath, *profile, applyFlags, scene.SpriteDir)
+		go w.run(p, scene.SpriteDir, stop)
+	}
+
+	final, err := p.Run()
+	if m.recorder != nil {
+		// Keep the recording even if the program failed
+		if rerr := m.recorder.Close(); rerr != nil {
+			fmt.Fprintf(os.Stderr, "Can't write recording: %v\n", rerr)
+		}
+		castFile.Close()
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Alas, there's been an error: %v\n", err)
 		os.Exit(1)
 	}
+
+	// Keep the session's scores and records, and show the table if it got
+	// onto it (or after a game, to see how it went)
+	results := final.(model).results
+	keepResults(results, len(results.Games) > 0, *inline || *screensaver)
 }
@@ -14,7 +14,13 @@ import (
 const (
 	minRunners = 3
 	maxRunners = 8
-	tickSpeed  = 100 * time.Millisecond // Animation frame rate
+	tickSpeed  = 100 * time.Millisecond // Fixed simulation step; velocities are cells per tick
+	defaultFPS = 30                     // Default render frame rate
+	maxFPS     = 240                    // Fastest frame rate allowed; much faster and a frame's delay rounds to nothing
+
+	// maxFrameTime caps how much elapsed time a single frame may feed into the
+	// simulation, so a stalled terminal doesn't trigger a burst of catch-up ticks.
+	maxFrameTime = 250 * time.Millisecond
 )
 
 // Define messages
@@ -23,6 +29,17 @@ type tickMsg time.Time
 // KeyMap for custom key bindings (optional but good practice)
 type keyMap struct {
 	Quit key.Binding
+
+	// Game mode (see game.go)
+	Jump    key.Binding
+	Duck    key.Binding
+	Faster  key.Binding
+	Slower  key.Binding
+	Restart key.Binding
+
+	// Races (see race.go)
+	LeftFoot  key.Binding
+	RightFoot key.Binding
 }
 
 var keys = keyMap{
@@ -30,6 +47,48 @@ var keys = keyMap{
 		key.WithKeys("q", "ctrl+c"),
 		key.WithHelp("q/ctrl+c", "quit"),
 	),
+	Jump:    key.NewBinding(key.WithKeys(" ", "up", "w"), key.WithHelp("space", "jump")),
+	Duck:    key.NewBinding(key.WithKeys("down", "s"), key.WithHelp("down", "duck")),
+	Faster:  key.NewBinding(key.WithKeys("right", "d"), key.WithHelp("right", "faster")),
+	Slower:  key.NewBinding(key.WithKeys("left", "a"), key.WithHelp("left", "slower")),
+	Restart: key.NewBinding(key.WithKeys("r", "enter"), key.WithHelp("r", "restart")),
+
+	LeftFoot:  key.NewBinding(key.WithKeys("z", "left"), key.WithHelp("z", "left foot")),
+	RightFoot: key.NewBinding(key.WithKeys("x", "right"), key.WithHelp("x", "right foot")),
+}
+
+// bindings maps config action names to the bindings they control.
+func (k *keyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":    &k.Quit,
+		"jump":    &k.Jump,
+		"duck":    &k.Duck,
+		"faster":  &k.Faster,
+		"slower":  &k.Slower,
+		"restart": &k.Restart,
+
+		"left_foot":  &k.LeftFoot,
+		"right_foot": &k.RightFoot,
+	}
+}
+
+// newKeyMap rebuilds the default key map with the given per-action overrides
+// (as loaded from the config file). Unknown actions are ignored; the config
+// loader rejects them before we get here.
+func newKeyMap(overrides map[string][]string) keyMap {
+	km := keys
+	bindings := km.bindings()
+	for action, ks := range overrides {
+		b, ok := bindings[action]
+		if !ok || len(ks) == 0 {
+			continue
+		}
+		*b = key.NewBinding(
+			key.WithKeys(ks...),
+			key.WithHelp(strings.Join(ks, "/"), b.Help().Desc),
+		)
+	}
+	return km
 }
 
 // model holds the application state
@@ -40,14 +99,105 @@ type model struct {
 	keys       keyMap
 	rng        *rand.Rand
 	err        error // To store potential errors
+
+	theme       themeName               // Which side of each runner's AdaptiveColor to use
+	background  []backgroundLayer       // Enabled scenery
+	layerDepths map[backgroundLayer]int // Depth overrides for background layers
+	tickRate    time.Duration           // Fixed simulation step
+	render      renderMode              // Positions are in this mode's pixels (see fieldSize)
+	colorTheme  colorThemeName          // Scenery and jersey colours (see theme.go)
+	lanes       int                     // Runner art is sized to fit this share of the height (see resize.go)
+	display     display                 // What the terminal can show, detected at startup
+	status      string                  // Shown on the bottom line, e.g. reload errors
+
+	// Screensaver mode (see screensaver.go)
+	screensaver bool
+	ticks       int // Simulation ticks so far
+	crowdTarget int // Runners come and go until there are this many
+	weather     weatherKind
+
+	// Banner mode and run limits (--inline, --height, --duration, --laps)
+	height      int           // Fixed scene height; 0 fills the terminal
+	runFor      time.Duration // Stop after this much simulated time; 0 runs until quit
+	laps        int           // Stop once a runner has finished this many laps; 0 runs until quit
+	clearOnExit bool          // Erase the scene when a limit stops it, rather than leaving the last frame
+	done        bool          // A run limit was reached
+
+	recorder *castRecorder // Records every frame (--record); nil when not recording
+
+	// Mouse interaction (see mouse.go)
+	selected   int      // ID of the selected runner, or noSelection
+	dragging   bool     // The selected runner follows the pointer
+	dragOffset Position // Where on the runner it was grabbed, in field pixels
+	speed      float64  // Simulation speed multiplier set with the scroll wheel
+
+	game *game     // Game mode (see game.go); nil when just watching
+	race *raceView // Multiplayer race (see race.go); nil when not racing
+
+	world *sharedWorld // The world every SSH session watches (serve --shared); nil to run our own
+
+	results scoreBoard // This session's game scores and lap records (see scores.go)
+
+	// Frame timing: frames arrive every frameDelay, while the simulation
+	// advances in fixed tickRate steps drained from accumulator.
+	frameDelay  time.Duration
+	lastFrame   time.Time
+	accumulator time.Duration
+}
+
+// initialModel creates the starting state of the application from a scene
+// (built-in defaults, optionally overridden by the config file) for a
+// terminal that can show d.
+func initialModel(scene Scene, d display) model {
+	return seededModel(scene, d, time.Now().UnixNano())
 }
 
-// initialModel creates the starting state of the application
-func initialModel() model {
+// seededModel is initialModel with a fixed random seed, so the same seed
+// always gives the same crowd and the same run.
+func seededModel(scene Scene, d display, seed int64) model {
 	m := model{
 		runners: make([]Runner, 0),
-		keys:    keys,
-		rng:     rand.New(rand.NewSource(time.Now().UnixNano())), // Seed RNG
+		rng:     rand.New(rand.NewSource(seed)),
+		render:  renderText, // Runners start out in cells
+
+		colorTheme: scene.ColorTheme,
+		display:    d,
+		selected:   noSelection,
+		speed:      1,
+		results:    newScoreBoard(),
+	}
+	m.addRunners(scene.Runners)
+	m.applyScene(scene) // Rescales the runners if the scene renders in pixels
+	return m
+}
+
+// addRunners creates the configured roster, or a random crowd if there is none.
+func (m *model) addRunners(roster []RunnerConfig) {
+	// A configured roster takes precedence over a random crowd
+	if len(roster) > 0 {
+		for i, rc := range roster {
+			runnerType := RunnerType(m.rng.Intn(int(TenKRunner + 1)))
+			if rc.Type != nil {
+				runnerType = *rc.Type
+			}
+			r, ok := newRunner(i, runnerType, m.rng)
+			if !ok {
+				continue
+			}
+			r.Name = rc.Name
+			if rc.Color != "" {
+				r.Color = lipgloss.AdaptiveColor{Light: string(rc.Color), Dark: string(rc.Color)}
+				r.FixedColor = true
+			} else {
+				r.Color = m.colors().jersey(m.rng)
+			}
+			if rc.Speed > 0 {
+				r.VelocityX = rc.Speed
+			}
+			r.Z = rc.Z
+			m.runners = append(m.runners, r)
+		}
+		return
 	}
 
 	// Determine number of runners
@@ -56,110 +206,166 @@ func initialModel() model {
 	// Create runners
 	for i := 0; i < numRunners; i++ {
 		runnerType := RunnerType(m.rng.Intn(int(TenKRunner + 1))) // Random type up to TenKRunner
-		art := getArtForType(runnerType)
-		if len(art) == 0 || len(art[0]) == 0 {
-			// Skip if art is invalid/empty
-			continue
+		if r, ok := newRunner(i, runnerType, m.rng); ok {
+			r.Color = m.colors().jersey(m.rng)
+			m.runners = append(m.runners, r)
 		}
-		artHeight := len(art[0]) // Assuming all frames have same height
+	}
+}
 
-		// Random initial position (ensure within typical terminal height)
-		// We'll adjust Y based on terminal height later in Update if needed
-		initialY := m.rng.Intn(20) + 1 // Start between line 1 and 20 initially
-		if initialY+artHeight > 24 {   // Avoid starting too low on common 24-line terms
-			initialY = 24 - artHeight
-		}
-		if initialY < 0 {
-			initialY = 0
-		}
+// applyScene applies the settings from a scene that can change while running.
+// The runner roster is only used when the model is first created.
+func (m *model) applyScene(scene Scene) {
+	m.keys = newKeyMap(scene.Keys)
+	m.frameDelay = time.Second / time.Duration(scene.FPS)
+	m.theme = scene.Theme
+	m.background = scene.Background
+	m.layerDepths = make(map[backgroundLayer]int, len(scene.LayerDepth))
+	for name, depth := range scene.LayerDepth {
+		m.layerDepths[backgroundLayer(name)] = depth
+	}
+	m.tickRate = scene.TickRate
+	m.setRenderMode(scene.Render)
+	m.lanes = scene.Lanes
+	if scene.ColorTheme != m.colorTheme {
+		m.colorTheme = scene.ColorTheme
+		m.recolorRunners()
+	}
+}
 
-		newRunner := Runner{
-			ID:              i,
-			Type:            runnerType,
-			Pos:             Position{X: float64(m.rng.Intn(10)), Y: float64(initialY)}, // Cast ints to float64
-			VelocityX:       m.rng.Float64()*1.5 + 0.5,                                  // Random horizontal speed (0.5 to 2.0 cells/tick)
-			VelocityY:       (m.rng.Float64() - 0.5) * 0.2,                              // Small random vertical drift (-0.1 to +0.1 cells/tick)
-			ArtFrames:       art,
-			CurrentFrameIdx: 0,
-			// Assign same random color for light/dark themes for simplicity
-			Color: lipgloss.AdaptiveColor{Light: fmt.Sprintf("%d", m.rng.Intn(230)+16), Dark: fmt.Sprintf("%d", m.rng.Intn(230)+16)}, // Use color strings
-			rng:   m.rng,                                                                                                             // Pass down RNG if needed per-runner
+// reloadArt swaps every runner to the current art for its type, at the size
+// that fits (see resize.go), keeping its position and animation phase. In a
+// game the player keeps its game art instead, on the ground.
+func (m *model) reloadArt() {
+	if g := m.game; g != nil {
+		// The player keeps its game art, picking up any change to it
+		g.run = getSpriteVariant(Jogger, sizeMedium)
+		if !g.ducked {
+			useSprite(&m.runners[0], g.run)
 		}
-		m.runners = append(m.runners, newRunner)
+		m.placePlayer()
+		return
 	}
+	for i := range m.runners {
+		useSprite(&m.runners[i], m.spriteFor(m.runners[i].Type))
+	}
+}
 
-	return m
+// useSprite gives a runner the art from sprite. The animation carries on
+// from the same point in the cycle, even if the new art has a different
+// number of frames.
+func useSprite(r *Runner, sprite *Sprite) {
+	if old := len(r.ArtFrames); old > 0 {
+		r.CurrentFrameIdx = r.CurrentFrameIdx * len(sprite.Frames) / old
+	}
+	r.ArtFrames = sprite.Frames
+	r.Masks = sprite.Masks
+	r.Palette = sprite.Palette
+	r.Transparent = sprite.transparentGlyph()
+	r.Size = sprite.Size
+	r.CurrentFrameIdx %= len(sprite.Frames)
 }
 
 // Init is the first command run by the Bubble Tea program.
 func (m model) Init() tea.Cmd {
-	return tickCmd() // Start the animation ticker
+	if m.screensaver {
+		return tea.Batch(tickCmd(m.frameDelay), tea.HideCursor)
+	}
+	return tickCmd(m.frameDelay) // Start the animation ticker
 }
 
 // Update handles messages and updates the model.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
+		oldWidth, oldHeight := m.fieldSize()
 		m.termWidth = msg.Width
 		m.termHeight = msg.Height
-		// Optional: Adjust runner Y positions if they are now off-screen due to resize
-		for i := range m.runners {
-			artHeight := len(m.runners[i].ArtFrames[0])
-			// Cast termHeight and artHeight for comparison and assignment
-			if m.runners[i].Pos.Y+float64(artHeight) >= float64(m.termHeight) {
-				m.runners[i].Pos.Y = float64(m.termHeight - artHeight - 1)
-				if m.runners[i].Pos.Y < 0 {
-					m.runners[i].Pos.Y = 0.0 // Use float64 zero value
-				}
-			}
+		if m.height > 0 && m.height < m.termHeight {
+			m.termHeight = m.height // A strip in the scrollback rather than the whole screen
+		}
+		if m.recorder != nil {
+			m.recorder.resize(m.termWidth, m.termHeight, time.Now())
 		}
+		m.relayout(oldWidth, oldHeight)
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.screensaver {
+			return m, tea.Quit // Any key wakes the screen up
+		}
 		switch {
 		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
+		case m.game != nil:
+			m.handleGameKey(msg)
+		case m.race != nil:
+			m.handleRaceKey(msg)
 		}
 
+	case tea.MouseMsg:
+		if m.screensaver {
+			return m, tea.Quit
+		}
+		if m.game == nil && m.race == nil {
+			m.handleMouse(msg)
+		}
+		return m, nil
+
 	case tickMsg:
-		// Update runner positions and frames
-		for i := range m.runners {
-			// Calculate new position (simple linear movement for now)
-			// Using float for position internally might be smoother, but int for rendering
-			// Position is already float64, just add velocity
-			m.runners[i].Pos.X += m.runners[i].VelocityX
-			m.runners[i].Pos.Y += m.runners[i].VelocityY
-
-			// Update animation frame
-			m.runners[i].CurrentFrameIdx = (m.runners[i].CurrentFrameIdx + 1) % len(m.runners[i].ArtFrames)
-
-			// Boundary check (wrap around screen width)
-			artWidth := 0
-			if len(m.runners[i].ArtFrames[m.runners[i].CurrentFrameIdx]) > 0 {
-				artWidth = lipgloss.Width(m.runners[i].ArtFrames[m.runners[i].CurrentFrameIdx][0]) // Width of first line
-			}
-			// Cast termWidth and artWidth for comparison and assignment
-			if m.runners[i].Pos.X > float64(m.termWidth) {
-				m.runners[i].Pos.X = float64(-artWidth) // Reset position off-screen left
-			}
+		if m.world != nil {
+			m.watchWorld() // The server runs the simulation
+			return m, tickCmd(m.frameDelay)
+		}
+		// Feed the real time elapsed since the last frame into the accumulator
+		// and advance the simulation in fixed steps, so runner speed doesn't
+		// depend on the render frame rate or on late frames.
+		now := time.Time(msg)
+		if m.lastFrame.IsZero() {
+			m.lastFrame = now
+		}
+		elapsed := now.Sub(m.lastFrame)
+		m.lastFrame = now
+		if elapsed > maxFrameTime {
+			elapsed = maxFrameTime
+		} else if elapsed < 0 {
+			elapsed = 0 // Clock went backwards; don't rewind the simulation
+		}
+		m.accumulator += time.Duration(float64(elapsed) * m.speed)
+		for m.accumulator >= m.tickRate {
+			m.step()
+			m.accumulator -= m.tickRate
+		}
+		if m.recorder != nil {
+			m.recorder.frame(m.View(), now)
+		}
+		if m.limitReached() {
+			m.done = true
+			return m, tea.Quit
+		}
+		return m, tickCmd(m.frameDelay) // Schedule next frame
 
-			// Boundary check for Y (bounce off top/bottom)
-			artHeight := 0
-			if len(m.runners[i].ArtFrames[m.runners[i].CurrentFrameIdx]) > 0 {
-				artHeight = len(m.runners[i].ArtFrames[m.runners[i].CurrentFrameIdx])
-			}
-			if m.runners[i].Pos.Y < 0 {
-				m.runners[i].Pos.Y = 0
-				m.runners[i].VelocityY *= -1 // Reverse vertical direction
-			} else if m.runners[i].Pos.Y+float64(artHeight) > float64(m.termHeight) {
-				m.runners[i].Pos.Y = float64(m.termHeight - artHeight)
-				if m.runners[i].Pos.Y < 0 { // Prevent getting stuck if art is taller than screen
-					m.runners[i].Pos.Y = 0
-				}
-				m.runners[i].VelocityY *= -1 // Reverse vertical direction
-			}
+	case reloadMsg:
+		// Keep running with the old art and settings if the reload failed
+		if msg.err != nil {
+			m.status = fmt.Sprintf("reload failed: %v", msg.err)
+			return m, nil
 		}
-		return m, tickCmd() // Schedule next tick
+		m.status = ""
+		setLoadedArt(msg.art)
+		m.applyScene(msg.scene)
+		m.reloadArt()
+		return m, nil
+
+	case raceStateMsg:
+		if m.race != nil && raceState(msg).valid() {
+			m.applyRaceState(raceState(msg))
+		}
+		return m, nil
+
+	case raceEndMsg:
+		m.err = msg.err
+		return m, tea.Quit
 
 	case error:
 		m.err = msg
@@ -174,152 +380,94 @@ func (m model) View() string {
 	if m.err != nil {
 		return fmt.Sprintf("Error: %v\n", m.err)
 	}
+	if m.done && m.clearOnExit {
+		return "" // Leaves nothing behind in the scrollback
+	}
 	if m.termWidth == 0 || m.termHeight == 0 {
-		return "Initializing or terminal size too small..."
+		return "Initializing..."
+	}
+	if m.tooSmall() {
+		return m.tooSmallView()
 	}
 
-	// --- View implementation using a styled screen buffer ---
+	return m.frame().render(m.display)
+}
 
-	// Helper struct to hold character and its style
-	type StyledCell struct {
-		Char  rune
-		Style lipgloss.Style
+// frame returns the styled cell buffer View() renders: the scene drawn back
+// to front (see render.go) with the status line on top.
+func (m model) frame() *frameBuffer {
+	buffer := m.buildFrame()
+
+	switch {
+	case m.game != nil:
+		m.drawGameOverlay(buffer)
+	case m.race != nil:
+		m.drawRaceOverlay(buffer)
+	default:
+		m.drawMouseOverlay(buffer)
 	}
 
-	// 1. Create the buffer (2D slice of StyledCell)
-	defaultStyle := lipgloss.NewStyle() // Default style for empty cells
-	buffer := make([][]StyledCell, m.termHeight)
-	for y := 0; y < m.termHeight; y++ {
-		buffer[y] = make([]StyledCell, m.termWidth)
-		for x := 0; x < m.termWidth; x++ {
-			buffer[y][x] = StyledCell{Char: ' ', Style: defaultStyle}
-		}
-	}
-	// 2. Draw static background elements
-	// Sun (top-right)
-	sunStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")) // Yellow
-	sunX := m.termWidth - lipgloss.Width(sunArt) - 2                  // Position from right edge
-	sunY := 1                                                         // Position from top edge
-	if sunY >= 0 && sunY < m.termHeight {
-		for i, char := range sunArt {
-			drawX := sunX + i
-			if drawX >= 0 && drawX < m.termWidth {
-				buffer[sunY][drawX] = StyledCell{Char: char, Style: sunStyle}
-			}
-		}
+	// Status line (e.g. reload errors) along the bottom row, over everything
+	if m.status != "" {
+		buffer.drawLine(0, m.termHeight-1, m.status, 0, fixedColor("9")) // Red
 	}
+	return buffer
+}
 
-	// Mountains (bottom) - Simple repeating pattern
-	mountainStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240")) // Gray
-	mountainY1 := m.termHeight - 2
-	mountainY2 := m.termHeight - 1
-	if mountainY1 >= 0 && mountainY2 >= 0 { // Ensure mountains are within bounds
-		for x := 0; x < m.termWidth; {
-			// Draw first line of mountain
-			for i, char := range mountainArtLine1 {
-				drawX := x + i
-				if drawX < m.termWidth {
-					buffer[mountainY1][drawX] = StyledCell{Char: char, Style: mountainStyle}
-				}
-			}
-			// Draw second line of mountain
-			for i, char := range mountainArtLine2 {
-				drawX := x + i
-				if drawX < m.termWidth {
-					buffer[mountainY2][drawX] = StyledCell{Char: char, Style: mountainStyle}
-				}
-			}
-			x += lipgloss.Width(mountainArtLine1) // Move to next mountain position
-		}
+// step advances the simulation by exactly one fixed tick.
+func (m *model) step() {
+	width, height := m.fieldSize()
+	m.ticks++
+	if m.game != nil {
+		m.stepGame()
+		return
 	}
-
-	// Birds (scattered - very basic)
-	birdStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("250")) // Light gray
-	birdPositions := []Position{{X: float64(m.termWidth / 4), Y: 3}, {X: float64(m.termWidth / 2), Y: 5}, {X: float64(m.termWidth * 3 / 4), Y: 2}}
-	for _, pos := range birdPositions {
-		drawX := int(pos.X)
-		drawY := int(pos.Y)
-		if drawY >= 0 && drawY < m.termHeight && drawX >= 0 && drawX < m.termWidth {
-			buffer[drawY][drawX] = StyledCell{Char: []rune(birdArt)[0], Style: birdStyle}
-		}
+	if m.race != nil {
+		return // The host runs the race
 	}
-
-	// 3. Draw each runner onto the buffer (over the background), storing style
-	// 2. Draw each runner onto the buffer, storing style
-	for _, r := range m.runners {
-		frame := r.ArtFrames[r.CurrentFrameIdx]
-		// Determine the correct style based on theme
-		runnerColor := r.Color.Light
-		if lipgloss.HasDarkBackground() {
-			runnerColor = r.Color.Dark
+	var wrapped []int
+	for i := range m.runners {
+		if m.dragging && m.runners[i].ID == m.selected {
+			continue // Held by the mouse
 		}
-		style := lipgloss.NewStyle().Foreground(lipgloss.Color(runnerColor))
-
-		for lineIdx, lineStr := range frame {
-			// Cast lineIdx for calculation, cast result to int for buffer index
-			targetY := int(r.Pos.Y + float64(lineIdx))
-			if targetY < 0 || targetY >= m.termHeight {
-				continue // Skip lines outside vertical bounds
-			}
-
-			currentXOffset := 0
-			for _, char := range lineStr {
-				charWidth := lipgloss.Width(string(char))
-				// Cast currentXOffset for calculation, cast result to int for buffer index
-				targetX := int(r.Pos.X + float64(currentXOffset))
-
-				for i := 0; i < charWidth; i++ {
-					// Cast i for calculation
-					drawX := targetX + i // targetX is already int, i is int
-					if drawX >= 0 && drawX < m.termWidth {
-						cellChar := ' ' // Default for multi-width cells
-						if i == 0 {
-							cellChar = char
-						}
-						// Store character and its style
-						// Ensure targetY is within buffer bounds (already checked)
-						// Ensure drawX is within buffer bounds (already checked)
-						buffer[targetY][drawX] = StyledCell{Char: cellChar, Style: style}
-					}
-				}
-				// currentXOffset needs to track the float position for accurate placement
-				// This buffer logic needs rethinking for float precision.
-				// Let's revert targetX calculation and drawX calculation to use int(r.Pos.X) for now
-				// and keep currentXOffset as int. This sacrifices sub-pixel accuracy in View
-				// for simpler buffer indexing, while Update still uses floats.
-
-				// Revert targetX calculation (line 198 change)
-				// targetX := int(r.Pos.X) + currentXOffset
-
-				// Revert drawX calculation (line 201 change) - it was already correct int+int
-				// drawX := targetX + i
-
-				// Keep currentXOffset as int
-				currentXOffset += charWidth
-			}
+		x := m.runners[i].Pos.X
+		stepRunner(&m.runners[i], width, height)
+		if m.runners[i].Pos.X < x {
+			m.runners[i].Laps++
+			m.timeLap(&m.runners[i])
+			wrapped = append(wrapped, i)
 		}
 	}
+	if m.screensaver {
+		m.evolve(wrapped)
+	}
+}
 
-	// 3. Convert buffer to a single string, applying styles
-	var finalView strings.Builder
-	for y := 0; y < m.termHeight; y++ {
-		for x := 0; x < m.termWidth; x++ {
-			cell := buffer[y][x]
-			finalView.WriteString(cell.Style.Render(string(cell.Char)))
-		}
-		// Add newline unless it's the last line
-		if y < m.termHeight-1 {
-			finalView.WriteString("\n")
+// limitReached reports whether the run has gone on for as long, or as many
+// laps, as it was asked to.
+func (m model) limitReached() bool {
+	if m.runFor > 0 && time.Duration(m.ticks)*m.tickRate >= m.runFor {
+		return true
+	}
+	if m.laps > 0 {
+		for _, r := range m.runners {
+			if r.Laps >= m.laps {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	return finalView.String()
-
+// interpolationAlpha reports how far (0..1) the render time is between the
+// previous simulation tick and the next one.
+func (m model) interpolationAlpha() float64 {
+	return float64(m.accumulator) / float64(m.tickRate)
 }
 
-// tickCmd sends a tick message after a delay
-func tickCmd() tea.Cmd {
-	return tea.Tick(tickSpeed, func(t time.Time) tea.Msg {
+// tickCmd sends a tick message after a delay (one render frame)
+func tickCmd(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
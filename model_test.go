@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel builds a model with a single runner moving at a known speed.
+func newTestModel(frameDelay time.Duration) model {
+	m := initialModel(defaultScene(), display{})
+	m.runners = []Runner{*newTestRunner(0, 5, 1.0, [][]string{{"x"}})}
+	m.termWidth = 1000
+	m.termHeight = 24
+	m.frameDelay = frameDelay
+	return m
+}
+
+// runFor feeds frames spaced frameDelay apart until total has elapsed or a
+// run limit stops the model.
+func runFor(m model, start time.Time, total time.Duration) model {
+	for t := time.Duration(0); t <= total && !m.done; t += m.frameDelay {
+		updated, _ := m.Update(tickMsg(start.Add(t)))
+		m = updated.(model)
+	}
+	return m
+}
+
+func TestSimulationSpeedIndependentOfFrameRate(t *testing.T) {
+	start := time.Now()
+	total := 2 * time.Second
+	wantX := float64(total/tickSpeed) * 1.0 // One cell per tick
+
+	for _, fps := range []int{5, 10, 30, 60, 144} {
+		m := runFor(newTestModel(time.Second/time.Duration(fps)), start, total)
+		if got := m.runners[0].Pos.X; math.Abs(got-wantX) > 1.0 {
+			t.Errorf("at %d fps runner travelled %v cells in %v, want ~%v", fps, got, total, wantX)
+		}
+	}
+}
+
+func TestLongFrameIsClamped(t *testing.T) {
+	start := time.Now()
+	m := newTestModel(time.Second / defaultFPS)
+	updated, _ := m.Update(tickMsg(start))
+	updated, _ = updated.(model).Update(tickMsg(start.Add(10 * time.Second)))
+	m = updated.(model)
+
+	maxTicks := float64(maxFrameTime / tickSpeed)
+	if got := m.runners[0].Pos.X; got > maxTicks {
+		t.Errorf("after a 10s stall runner moved %v cells, want at most %v", got, maxTicks)
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	start := time.Now()
+
+	m := newTestModel(time.Second / defaultFPS)
+	m.runFor = time.Second
+	m = runFor(m, start, 2*time.Second)
+	if !m.done || m.ticks != int(time.Second/tickSpeed) {
+		t.Errorf("--duration 1s: done %v after %d ticks, want done after %d", m.done, m.ticks, time.Second/tickSpeed)
+	}
+
+	m = newTestModel(time.Second / defaultFPS)
+	m.termWidth = 10
+	m.laps = 2
+	m = runFor(m, start, 5*time.Second)
+	if !m.done || m.runners[0].Laps != 2 {
+		t.Errorf("--laps 2: done %v after %d laps, want done after 2", m.done, m.runners[0].Laps)
+	}
+}
+
+func TestInlineStrip(t *testing.T) {
+	m := newTestModel(time.Second / defaultFPS)
+	m.height = 8
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 20, Height: 40})
+	m = updated.(model)
+	if lines := strings.Count(m.View(), "\n") + 1; lines != 8 {
+		t.Errorf("inline view is %d lines, want 8", lines)
+	}
+	if m.runners[0].Pos.Y+1 > 8 {
+		t.Errorf("runner at row %v, want it inside the strip", m.runners[0].Pos.Y)
+	}
+
+	m.done = true
+	if view := m.View(); view == "" {
+		t.Errorf("finished view is empty, want the last frame")
+	}
+	m.clearOnExit = true
+	if view := m.View(); view != "" {
+		t.Errorf("finished view with --clear = %q, want empty", view)
+	}
+}
+
+func TestSpriteSizeFitsLane(t *testing.T) {
+	m := initialModel(defaultScene(), display{})
+	m.runners = []Runner{*newTestRunner(0, 0, 0, joggerArt)}
+	m.runners[0].Type = Jogger
+	m.runners[0].CurrentFrameIdx = 2 // Half way through the four-frame cycle
+
+	resize := func(height, lanes int) Runner {
+		m.lanes = lanes
+		updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: height})
+		m = updated.(model)
+		return m.runners[0]
+	}
+	if r := resize(24, 1); r.Size != sizeLarge {
+		t.Errorf("24 lines, 1 lane: size %v, want large", r.Size)
+	}
+	if r := resize(24, 4); r.Size != sizeMedium || r.CurrentFrameIdx != 1 {
+		t.Errorf("24 lines, 4 lanes: size %v frame %d, want medium at frame 1 (same phase)", r.Size, r.CurrentFrameIdx)
+	}
+	if r := resize(10, 5); r.Size != sizeTiny || len(r.ArtFrames[0]) != 1 {
+		t.Errorf("10 lines, 5 lanes: size %v, want tiny single-line art", r.Size)
+	}
+	if r := resize(24, 1); r.Size != sizeLarge || r.CurrentFrameIdx != 2 {
+		t.Errorf("back to 24 lines: size %v frame %d, want large at frame 2", r.Size, r.CurrentFrameIdx)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Mouse interaction ---
+//
+// Clicking a runner selects it (its name or type is shown above it) and
+// dragging moves it; clicking the sky anywhere else spawns a new runner
+// there. The scroll wheel speeds the whole simulation up or slows it down.
+// Hit-testing uses each runner's bounding box: the width of the widest line
+// and the height of its current frame, at the position it's drawn at.
+
+const (
+	speedStep = 1.25 // Each scroll notch multiplies or divides the speed by this
+	minSpeed  = 0.125
+	maxSpeed  = 8
+)
+
+// noSelection is the selected runner ID when no runner is selected.
+const noSelection = -1
+
+// handleMouse applies a mouse event outside screensaver mode.
+func (m *model) handleMouse(msg tea.MouseMsg) {
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp && msg.Action == tea.MouseActionPress:
+		m.speed = math.Min(m.speed*speedStep, maxSpeed)
+	case msg.Button == tea.MouseButtonWheelDown && msg.Action == tea.MouseActionPress:
+		m.speed = math.Max(m.speed/speedStep, minSpeed)
+
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		if i, ok := m.runnerAt(msg.X, msg.Y); ok {
+			r := &m.runners[i]
+			px, py := m.render.cellPixels()
+			m.selected, m.dragging = r.ID, true
+			r.LapStart = -1 // Carried, not run
+			m.dragOffset = Position{X: float64(msg.X*px) - r.Pos.X, Y: float64(msg.Y*py) - r.Pos.Y}
+			return
+		}
+		m.selected = noSelection
+		m.spawnAt(msg.X, msg.Y)
+
+	case msg.Action == tea.MouseActionMotion && m.dragging:
+		if i, ok := m.runnerByID(m.selected); ok {
+			px, py := m.render.cellPixels()
+			r := &m.runners[i]
+			r.Pos = Position{X: float64(msg.X*px) - m.dragOffset.X, Y: float64(msg.Y*py) - m.dragOffset.Y}
+			r.PrevPos = r.Pos // Follow the pointer exactly, without interpolating
+		}
+
+	case msg.Action == tea.MouseActionRelease:
+		m.dragging = false
+	}
+}
+
+// runnerBounds is the box a runner covers when drawn at pos, in field pixels.
+func runnerBounds(r *Runner, pos Position) image.Rectangle {
+	frame := r.ArtFrames[r.CurrentFrameIdx]
+	width := 0
+	for _, line := range frame {
+		if w := lipgloss.Width(line); w > width {
+			width = w
+		}
+	}
+	x, y := int(math.Floor(pos.X)), int(math.Floor(pos.Y))
+	return image.Rect(x, y, x+width, y+len(frame))
+}
+
+// runnerAt returns the index of the frontmost runner covering terminal cell
+// (x, y), if any.
+func (m model) runnerAt(x, y int) (int, bool) {
+	px, py := m.render.cellPixels()
+	cell := image.Rect(x*px, y*py, (x+1)*px, (y+1)*py)
+	alpha := m.interpolationAlpha()
+
+	// Back to front, in the order buildFrame draws them
+	order := make([]int, len(m.runners))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ra, rb := &m.runners[order[a]], &m.runners[order[b]]
+		if ra.Z != rb.Z {
+			return ra.Z < rb.Z
+		}
+		return interpolatedPos(ra, alpha).Y+float64(len(ra.ArtFrames[ra.CurrentFrameIdx])) <
+			interpolatedPos(rb, alpha).Y+float64(len(rb.ArtFrames[rb.CurrentFrameIdx]))
+	})
+	for i := len(order) - 1; i >= 0; i-- {
+		r := &m.runners[order[i]]
+		if runnerBounds(r, interpolatedPos(r, alpha)).Overlaps(cell) {
+			return order[i], true
+		}
+	}
+	return 0, false
+}
+
+// runnerByID returns the index of the runner with the given ID, if it's
+// still running.
+func (m model) runnerByID(id int) (int, bool) {
+	for i, r := range m.runners {
+		if r.ID == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// spawnAt adds a random runner with its top-left corner at terminal cell
+// (x, y), moved up if it would hang off the bottom.
+func (m *model) spawnAt(x, y int) {
+	r, ok := m.spawnRunner()
+	if !ok {
+		return
+	}
+	px, py := m.render.cellPixels()
+	_, height := m.fieldSize()
+	r.Pos = Position{X: float64(x * px), Y: math.Max(0, math.Min(float64(y*py), float64(height-len(r.ArtFrames[0]))))}
+	r.PrevPos = r.Pos
+	m.runners = append(m.runners, r)
+}
+
+// drawMouseOverlay draws the selected runner's label above it and the
+// simulation speed when the wheel has changed it, onto a buffer of cells.
+func (m model) drawMouseOverlay(b *frameBuffer) {
+	if i, ok := m.runnerByID(m.selected); ok {
+		r := &m.runners[i]
+		label := r.Name
+		if label == "" {
+			label = r.Type.String()
+		}
+		px, py := m.render.cellPixels()
+		box := runnerBounds(r, interpolatedPos(r, m.interpolationAlpha()))
+		x, y := box.Min.X/px, box.Min.Y/py-1
+		if y < 0 {
+			y = (box.Max.Y + py - 1) / py // No room above; label it underneath
+		}
+		b.drawLine(x, y, "["+label+"]", 0, fixedColor(m.jersey(r)))
+	}
+	if m.speed != 1 {
+		b.drawLine(0, 0, fmt.Sprintf("speed x%.2g", m.speed), 0, fixedColor(m.colors().Birds))
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mouseModel is a 30x10 model with two overlapping runners standing still:
+// A at (2, 2) and B, nearer, at (4, 3).
+func mouseModel() model {
+	a := *newTestRunner(2, 2, 0, [][]string{{"AAAA", "AAAA"}})
+	b := *newTestRunner(4, 3, 0, [][]string{{"BBBB", "BBBB"}})
+	a.ID, b.ID = 1, 2
+	a.Name = "Ana"
+	m := sceneModel(a, b)
+	m.termWidth, m.termHeight = 30, 10
+	return m
+}
+
+// click sends a mouse event to m.
+func click(m model, x, y int, button tea.MouseButton, action tea.MouseAction) model {
+	updated, _ := m.Update(tea.MouseMsg{X: x, Y: y, Button: button, Action: action})
+	return updated.(model)
+}
+
+func TestRunnerAtPicksFrontmost(t *testing.T) {
+	m := mouseModel()
+	tests := []struct {
+		x, y   int
+		wantID int // noSelection for empty sky
+	}{
+		{2, 2, 1},
+		{5, 2, 1},
+		{5, 3, 2}, // Both cover it; B is lower on screen, so in front
+		{7, 4, 2},
+		{8, 4, noSelection},
+		{1, 2, noSelection},
+	}
+	for _, tt := range tests {
+		id := noSelection
+		if i, ok := m.runnerAt(tt.x, tt.y); ok {
+			id = m.runners[i].ID
+		}
+		if id != tt.wantID {
+			t.Errorf("runnerAt(%d, %d) = runner %d, want %d", tt.x, tt.y, id, tt.wantID)
+		}
+	}
+}
+
+func TestClickSelectsAndDragMoves(t *testing.T) {
+	m := click(mouseModel(), 3, 2, tea.MouseButtonLeft, tea.MouseActionPress)
+	if m.selected != 1 || !m.dragging {
+		t.Fatalf("after clicking A: selected %d dragging %v, want A being dragged", m.selected, m.dragging)
+	}
+	if rows := plainRows(m.frame()); !strings.Contains(rows[1], "[Ana]") {
+		t.Errorf("row above A = %q, want its name", rows[1])
+	}
+
+	m = click(m, 13, 6, tea.MouseButtonLeft, tea.MouseActionMotion)
+	if got := m.runners[0].Pos; got != (Position{X: 12, Y: 6}) {
+		t.Errorf("dragged A to %+v, want (12, 6): it keeps the point it was grabbed by", got)
+	}
+	m.step()
+	if got := m.runners[0].Pos; got != (Position{X: 12, Y: 6}) {
+		t.Errorf("A moved to %+v while held", got)
+	}
+
+	m = click(m, 13, 6, tea.MouseButtonLeft, tea.MouseActionRelease)
+	m = click(m, 14, 7, tea.MouseButtonLeft, tea.MouseActionMotion)
+	if m.dragging || m.runners[0].Pos != (Position{X: 12, Y: 6}) {
+		t.Errorf("A still follows the mouse after the button was released")
+	}
+}
+
+func TestClickSkySpawnsRunner(t *testing.T) {
+	m := click(mouseModel(), 20, 1, tea.MouseButtonLeft, tea.MouseActionPress)
+	if len(m.runners) != 3 {
+		t.Fatalf("got %d runners after clicking the sky, want 3", len(m.runners))
+	}
+	r := m.runners[2]
+	if r.Pos.X != 20 || r.Pos.Y+float64(len(r.ArtFrames[0])) > 10 {
+		t.Errorf("new runner at %+v, want at column 20 and on screen", r.Pos)
+	}
+	if r.ID == 1 || r.ID == 2 {
+		t.Errorf("new runner has ID %d, already taken", r.ID)
+	}
+	if m.selected != noSelection {
+		t.Errorf("clicking the sky left runner %d selected", m.selected)
+	}
+}
+
+func TestScrollChangesSpeed(t *testing.T) {
+	m := click(mouseModel(), 0, 0, tea.MouseButtonWheelUp, tea.MouseActionPress)
+	if m.speed != speedStep {
+		t.Errorf("speed after scrolling up = %v, want %v", m.speed, speedStep)
+	}
+	if rows := plainRows(m.frame()); !strings.HasPrefix(rows[0], "speed x1.2") {
+		t.Errorf("top row = %q, want the speed shown", rows[0])
+	}
+	for i := 0; i < 50; i++ {
+		m = click(m, 0, 0, tea.MouseButtonWheelDown, tea.MouseActionPress)
+	}
+	if m.speed != minSpeed {
+		t.Errorf("speed after scrolling down a lot = %v, want the minimum %v", m.speed, minSpeed)
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Multiplayer races (consolerunner host / join) ---
+//
+// One player hosts a race and the others join it over the network. Each
+// runner only moves when its player runs it: pressing the left-foot and
+// right-foot keys in turn takes strides, and the faster they alternate the
+// faster it goes. Pressing the same foot twice doesn't count. The host runs
+// the simulation and sends everyone the state after every tick, so every
+// screen agrees on who's winning; the other players' programs just draw it
+// and send their strides back (see racenet.go).
+//
+// Players who join during a race wait for the next one. Once the first
+// runner is over the line the rest have raceCutoffTicks to follow, or don't
+// finish, so a player who's wandered off doesn't hold everyone up; the host
+// can also start again at any time.
+//
+// The screen is the track, one lane per runner, with the finish line at the
+// right edge however long the race is.
+
+const (
+	defaultRaceLength  = 200  // Cells from the start to the finish
+	raceStride         = 0.4  // Speed each stride adds, cells per tick
+	raceMaxSpeed       = 3.0  // Cells per tick
+	raceDrag           = 0.85 // Share of its speed a runner keeps from one tick to the next
+	raceCountdownTicks = 30   // From the start of the countdown to "go"
+	raceCutoffTicks    = 150  // After the first finish, for the rest to finish
+	maxRacers          = 8
+	raceFinishGlyph    = '|'
+	raceFinishColor    = "255"
+)
+
+// racePhase is where a race is up to.
+type racePhase string
+
+const (
+	raceLobby     racePhase = "lobby"     // Waiting for the host to start
+	raceCountdown racePhase = "countdown" // Starting; strides don't count yet
+	raceRunning   racePhase = "running"
+	raceFinished  racePhase = "finished" // Everyone is over the line
+)
+
+// raceFoot is the foot a stride is taken with.
+type raceFoot string
+
+const (
+	footLeft  raceFoot = "left"
+	footRight raceFoot = "right"
+)
+
+// errRaceFull is returned when a player tries to join a full race.
+var errRaceFull = errors.New("the race is full")
+
+// raceState is the whole race, as the host sends it after every tick.
+type raceState struct {
+	Phase    racePhase     `json:"phase"`
+	Length   float64       `json:"length"`    // Cells from the start to the finish
+	Tick     int           `json:"tick"`      // Ticks since the countdown started
+	TickRate time.Duration `json:"tick_rate"` // The host's tick, in nanoseconds
+	Players  []racePlayer  `json:"players"`
+}
+
+// racePlayer is one player's runner.
+type racePlayer struct {
+	ID       int                    `json:"id"`
+	Name     string                 `json:"name"`
+	Color    lipgloss.AdaptiveColor `json:"color"`
+	Distance float64                `json:"distance"` // Cells from the start
+	Speed    float64                `json:"speed"`    // Cells per tick
+	Strides  int                    `json:"strides"`  // Counted strides, which also pick the animation frame
+	Foot     raceFoot               `json:"foot,omitempty"`
+	Finish   int                    `json:"finish,omitempty"`  // Tick it crossed the line; 0 while still running
+	DNF      bool                   `json:"dnf,omitempty"`     // Still running at the cutoff
+	Waiting  bool                   `json:"waiting,omitempty"` // Joined during a race; runs in the next one
+}
+
+// racing reports whether the player is still in the race: not over the
+// line, out of it or waiting for the next one.
+func (p racePlayer) racing() bool {
+	return p.Finish == 0 && !p.DNF && !p.Waiting
+}
+
+// player returns the player with the given ID.
+func (s *raceState) player(id int) (*racePlayer, bool) {
+	for i := range s.Players {
+		if s.Players[i].ID == id {
+			return &s.Players[i], true
+		}
+	}
+	return nil, false
+}
+
+// addPlayer adds a runner to the race and returns its ID. During a race
+// the runner waits at the start for the next one.
+func (s *raceState) addPlayer(name string, color lipgloss.AdaptiveColor) (int, error) {
+	if len(s.Players) >= maxRacers {
+		return 0, errRaceFull
+	}
+	id := 1
+	for _, p := range s.Players {
+		if p.ID >= id {
+			id = p.ID + 1
+		}
+	}
+	waiting := s.Phase == raceCountdown || s.Phase == raceRunning
+	s.Players = append(s.Players, racePlayer{ID: id, Name: name, Color: color, Waiting: waiting})
+	return id, nil
+}
+
+// removePlayer takes a runner out of the race.
+func (s *raceState) removePlayer(id int) {
+	kept := s.Players[:0]
+	for _, p := range s.Players {
+		if p.ID != id {
+			kept = append(kept, p)
+		}
+	}
+	s.Players = kept
+	s.checkFinished()
+}
+
+// start lines everyone up, including anyone waiting, and starts the
+// countdown. Starting during a race abandons it and starts again.
+func (s *raceState) start() {
+	for i := range s.Players {
+		p := &s.Players[i]
+		p.Distance, p.Speed, p.Strides, p.Foot, p.Finish = 0, 0, 0, "", 0
+		p.DNF, p.Waiting = false, false
+	}
+	s.Phase, s.Tick = raceCountdown, 0
+}
+
+// stride takes a stride with a player's runner, if it's with the other
+// foot from the last one.
+func (s *raceState) stride(id int, foot raceFoot) {
+	p, ok := s.player(id)
+	if !ok || s.Phase != raceRunning || !p.racing() || foot == p.Foot {
+		return
+	}
+	p.Foot = foot
+	p.Strides++
+	p.Speed = math.Min(p.Speed+raceStride, raceMaxSpeed)
+}
+
+// step advances the race by one tick.
+func (s *raceState) step() {
+	switch s.Phase {
+	case raceCountdown:
+		s.Tick++
+		if s.Tick >= raceCountdownTicks {
+			s.Phase = raceRunning
+		}
+	case raceRunning:
+		s.Tick++
+		for i := range s.Players {
+			p := &s.Players[i]
+			if !p.racing() {
+				continue
+			}
+			p.Distance += p.Speed
+			p.Speed *= raceDrag
+			if p.Distance >= s.Length {
+				p.Distance, p.Speed, p.Finish = s.Length, 0, s.Tick
+			}
+		}
+		if first := s.firstFinish(); first != 0 && s.Tick-first >= raceCutoffTicks {
+			for i := range s.Players {
+				if s.Players[i].racing() {
+					s.Players[i].DNF = true
+				}
+			}
+		}
+		s.checkFinished()
+	}
+}
+
+// firstFinish returns the tick the first runner crossed the line, or 0 if
+// nobody has yet.
+func (s raceState) firstFinish() int {
+	first := 0
+	for _, p := range s.Players {
+		if p.Finish != 0 && (first == 0 || p.Finish < first) {
+			first = p.Finish
+		}
+	}
+	return first
+}
+
+// checkFinished ends a running race once nobody left in it is still racing.
+func (s *raceState) checkFinished() {
+	if s.Phase != raceRunning {
+		return
+	}
+	for _, p := range s.Players {
+		if p.racing() {
+			return
+		}
+	}
+	s.Phase = raceFinished
+}
+
+// valid reports whether a state from the host is one that can be drawn: a
+// track with some length and no more runners than a race takes, none of
+// them having taken negative strides. The host is another player's program,
+// so it isn't taken on trust.
+func (s raceState) valid() bool {
+	if !(s.Length > 0) || len(s.Players) > maxRacers || s.TickRate < 0 {
+		return false
+	}
+	for _, p := range s.Players {
+		if p.Strides < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// elapsed returns how long a number of the host's ticks takes.
+func (s raceState) elapsed(ticks int) time.Duration {
+	return time.Duration(ticks) * s.TickRate
+}
+
+// standings returns the players in race order: those over the line by
+// finishing time, then those still running and those that didn't finish by
+// how far they've got, then those waiting for the next race.
+func (s raceState) standings() []racePlayer {
+	group := func(p racePlayer) int {
+		switch {
+		case p.Finish != 0:
+			return 0
+		case p.Waiting:
+			return 3
+		case p.DNF:
+			return 2
+		}
+		return 1
+	}
+	players := append([]racePlayer(nil), s.Players...)
+	sort.SliceStable(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		switch {
+		case group(a) != group(b):
+			return group(a) < group(b)
+		case a.Finish != 0:
+			return a.Finish < b.Finish
+		}
+		return a.Distance > b.Distance
+	})
+	return players
+}
+
+// clone returns a copy of s that doesn't share its players.
+func (s raceState) clone() raceState {
+	s.Players = append([]racePlayer(nil), s.Players...)
+	return s
+}
+
+// raceView is the model's side of a race: the latest state from the host
+// and how to reach it.
+type raceView struct {
+	state   raceState
+	id      int                 // This player's runner
+	host    bool                // This player is hosting, and starts the races
+	address string              // Where others can join, shown while waiting
+	send    func(m raceMessage) // Sends a stride or start to the host
+}
+
+// raceStateMsg is a new race state from the host.
+type raceStateMsg raceState
+
+// raceEndMsg says the connection to the host has gone.
+type raceEndMsg struct{ err error }
+
+// startRace puts the model into race mode, with no runners until the first
+// state arrives from the host.
+func (m *model) startRace(view *raceView) {
+	m.race = view
+	m.runners = nil
+	m.selected = noSelection
+}
+
+// applyRaceState lays out a new race state: one lane per runner, from the
+// top, with each runner's distance along the track scaled to the width of
+// the screen. Runners move smoothly from where the previous state had them.
+func (m *model) applyRaceState(s raceState) {
+	if old, ok := m.race.state.player(m.race.id); ok && old.Finish == 0 {
+		if p, ok := s.player(m.race.id); ok && p.Finish != 0 {
+			m.recordRace(s, *p)
+		}
+	}
+	m.race.state = s
+	m.lanes = len(s.Players)
+	if m.lanes == 0 {
+		m.lanes = 1
+	}
+	width, _ := m.fieldSize()
+	lane := m.laneHeight()
+	sprite := m.spriteFor(Jogger)
+
+	runners := make([]Runner, len(s.Players))
+	for i, p := range s.Players {
+		r := Runner{ID: p.ID, Type: Jogger}
+		existing, seen := m.runnerByID(p.ID)
+		if seen {
+			r = m.runners[existing]
+		}
+		if r.ArtFrames == nil || r.Size != sprite.Size {
+			useSprite(&r, sprite)
+		}
+		r.Name, r.Color, r.FixedColor = p.Name, p.Color, true
+		r.CurrentFrameIdx = p.Strides % len(r.ArtFrames)
+
+		room := float64(width - runnerBounds(&r, Position{}).Dx() - 1) // Up to the finish line
+		r.PrevPos = r.Pos
+		r.Pos = Position{
+			X: math.Max(0, room) * p.Distance / s.Length,
+			Y: float64((i+1)*lane - len(r.ArtFrames[r.CurrentFrameIdx])),
+		}
+		if !seen {
+			r.PrevPos = r.Pos
+		}
+		runners[i] = r
+	}
+	m.runners = runners
+	m.accumulator = 0 // Interpolate from the previous state over the next tick
+}
+
+// handleRaceKey applies a key press in a race.
+func (m *model) handleRaceKey(msg tea.KeyMsg) {
+	switch {
+	case key.Matches(msg, m.keys.LeftFoot):
+		m.race.send(raceMessage{Type: raceMsgStride, Foot: footLeft})
+	case key.Matches(msg, m.keys.RightFoot):
+		m.race.send(raceMessage{Type: raceMsgStride, Foot: footRight})
+	case key.Matches(msg, m.keys.Restart) && m.race.host:
+		m.race.send(raceMessage{Type: raceMsgStart})
+	}
+}
+
+// drawRace draws the finish line.
+func (m model) drawRace(b *frameBuffer) {
+	for y := 0; y < b.height; y++ {
+		b.set(b.width-1, y, raceFinishGlyph, raceFinishColor)
+	}
+}
+
+// drawRaceOverlay draws the runners' names and places, and what's going on:
+// who's waiting, the countdown or the results.
+func (m model) drawRaceOverlay(b *frameBuffer) {
+	v := m.race
+	s := v.state
+	_, py := m.render.cellPixels()
+	place := map[int]int{}
+	for i, p := range s.standings() {
+		place[p.ID] = i + 1
+	}
+	for i, p := range s.Players {
+		label := p.Name
+		if p.ID == v.id {
+			label += " (you)"
+		}
+		switch {
+		case p.Waiting:
+			label += " (next race)"
+		case p.DNF:
+			label = "DNF " + label
+		case s.Phase != raceLobby:
+			label = fmt.Sprintf("%s %s", ordinal(place[p.ID]), label)
+		}
+		b.drawLine(b.width-1-lipgloss.Width(label), i*m.laneHeight()/py, label, 0, fixedColor(m.jersey(&m.runners[i])))
+	}
+
+	var lines []string
+	switch s.Phase {
+	case raceLobby:
+		lines = append(lines, fmt.Sprintf("%d runner(s) ready", len(s.Players)))
+		if v.host {
+			lines = append(lines, "Join with: consolerunner join "+v.address, m.keys.Restart.Help().Key+" to start the race")
+		} else {
+			lines = append(lines, "Waiting for the host to start the race")
+		}
+	case raceCountdown:
+		left := s.elapsed(raceCountdownTicks - s.Tick)
+		lines = append(lines, fmt.Sprintf("%.0f", math.Ceil(left.Seconds())))
+	case raceRunning:
+		if s.Tick < raceCountdownTicks+10 {
+			lines = append(lines, "GO!")
+		}
+		if first := s.firstFinish(); first != 0 {
+			left := s.elapsed(first + raceCutoffTicks - s.Tick)
+			lines = append(lines, fmt.Sprintf("%.0fs left to finish", math.Ceil(left.Seconds())))
+		}
+	case raceFinished:
+		lines = append(lines, "RESULTS")
+		for i, p := range s.standings() {
+			switch {
+			case p.Finish != 0:
+				lines = append(lines, fmt.Sprintf("%d. %s  %.1fs", i+1, p.Name, s.elapsed(p.Finish-raceCountdownTicks).Seconds()))
+			case !p.Waiting:
+				lines = append(lines, fmt.Sprintf("%d. %s  DNF", i+1, p.Name))
+			}
+		}
+		if v.host {
+			lines = append(lines, m.keys.Restart.Help().Key+" to race again")
+		}
+	}
+	if s.Phase == raceLobby || s.Phase == raceFinished {
+		lines = append(lines, fmt.Sprintf("%s / %s to run, %s to quit",
+			m.keys.LeftFoot.Help().Key, m.keys.RightFoot.Help().Key, m.keys.Quit.Help().Key))
+	}
+	top := (b.height - len(lines)) / 2
+	for i, line := range lines {
+		b.drawLine((b.width-lipgloss.Width(line))/2, top+i, line, 0, fixedColor(m.colors().Sun))
+	}
+}
+
+// ordinal writes a place: 1st, 2nd, 3rd, 4th and so on.
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
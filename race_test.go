@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// runningRace returns a race with the given players, counted down and running.
+func runningRace(names ...string) raceState {
+	s := raceState{Phase: raceLobby, Length: 20, TickRate: tickSpeed}
+	for _, name := range names {
+		s.addPlayer(name, lipgloss.AdaptiveColor{Light: "1", Dark: "1"})
+	}
+	s.start()
+	for s.Phase == raceCountdown {
+		s.step()
+	}
+	return s
+}
+
+func TestRaceStridesMustAlternate(t *testing.T) {
+	s := raceState{Phase: raceLobby, Length: 20}
+	id, _ := s.addPlayer("Ana", lipgloss.AdaptiveColor{})
+	s.start()
+	s.stride(id, footLeft)
+	if p, _ := s.player(id); p.Strides != 0 {
+		t.Error("a stride counted during the countdown")
+	}
+
+	s = runningRace("Ana")
+	for _, foot := range []raceFoot{footLeft, footLeft, footRight, footRight, footLeft} {
+		s.stride(1, foot)
+	}
+	p, _ := s.player(1)
+	speed := 0.0
+	for i := 0; i < 3; i++ {
+		speed += raceStride
+	}
+	if p.Strides != 3 || p.Speed != speed {
+		t.Errorf("strides %d at speed %v, want 3 at %v: repeats of the same foot don't count", p.Strides, p.Speed, speed)
+	}
+	s.step()
+	if p.Distance != speed || p.Speed != speed*raceDrag {
+		t.Errorf("after a tick at %v, speed %v, want %v and slowing to %v", p.Distance, p.Speed, speed, speed*raceDrag)
+	}
+}
+
+func TestRaceFinishOrder(t *testing.T) {
+	s := runningRace("Ana", "Bo", "Cy")
+	s.Players[0].Distance, s.Players[1].Distance, s.Players[2].Distance = 10, 19.5, 5
+	s.Players[0].Speed, s.Players[1].Speed = 1, 1
+	s.step()
+	if s.Phase != raceRunning || s.Players[1].Finish != s.Tick {
+		t.Fatalf("phase %s with Bo finished at %d, want still running with Bo over the line", s.Phase, s.Players[1].Finish)
+	}
+	if got := s.standings(); got[0].Name != "Bo" || got[1].Name != "Ana" || got[2].Name != "Cy" {
+		t.Errorf("standings %v, want Bo, Ana, Cy", got)
+	}
+
+	// The race ends once the last runner still in it is over the line
+	s.Players[0].Distance, s.Players[0].Speed = 20, 1
+	s.step()
+	s.removePlayer(3)
+	if s.Phase != raceFinished {
+		t.Errorf("phase %s after everyone left finished, want finished", s.Phase)
+	}
+}
+
+func TestRaceIdlePlayerDoesNotHoldUp(t *testing.T) {
+	s := runningRace("Ana", "Bo")
+	s.Players[0].Distance, s.Players[0].Speed = 19.5, 1
+	s.step()
+	for i := 1; i < raceCutoffTicks && s.Phase == raceRunning; i++ {
+		s.step()
+	}
+	if s.Phase != raceRunning {
+		t.Fatalf("race ended %d ticks after the first finish, want %d", s.Tick-s.Players[0].Finish, raceCutoffTicks)
+	}
+	s.step()
+	if s.Phase != raceFinished || !s.Players[1].DNF {
+		t.Errorf("phase %s with Bo %+v at the cutoff, want finished with Bo DNF", s.Phase, s.Players[1])
+	}
+	if got := s.standings(); got[0].Name != "Ana" || got[1].Name != "Bo" {
+		t.Errorf("standings %v, want Ana then Bo", got)
+	}
+}
+
+func TestRaceJoinDuringRaceWaits(t *testing.T) {
+	s := runningRace("Ana")
+	id, _ := s.addPlayer("Bo", lipgloss.AdaptiveColor{})
+	s.stride(id, footLeft)
+	if p, _ := s.player(id); !p.Waiting || p.Strides != 0 {
+		t.Fatalf("Bo joined during the race as %+v, want waiting with no strides", p)
+	}
+	s.Players[0].Distance, s.Players[0].Speed = 19.5, 1
+	s.step()
+	if s.Phase != raceFinished {
+		t.Fatalf("phase %s once Ana finished, want finished without waiting for Bo", s.Phase)
+	}
+	s.start()
+	if p, _ := s.player(id); p.Waiting || s.Phase != raceCountdown {
+		t.Errorf("after starting again Bo is %+v in phase %s, want in the countdown", p, s.Phase)
+	}
+}
+
+func TestRaceHostRestartsRunningRace(t *testing.T) {
+	s := runningRace("Ana", "Bo")
+	s.stride(1, footLeft)
+	s.step()
+	s.start()
+	if s.Phase != raceCountdown || s.Tick != 0 || s.Players[0].Distance != 0 {
+		t.Errorf("after starting mid-race: %+v, want everyone back in the countdown", s)
+	}
+}
+
+func TestRaceOverLocalhost(t *testing.T) {
+	h := newRaceHost(20, tickSpeed, colorThemes[colorThemeClassic], 1)
+	host, _ := h.join("Ana")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go h.serve(l)
+
+	c, err := dialRace(l.Addr().String(), "Bo")
+	if err != nil {
+		t.Fatalf("dialRace() error = %v", err)
+	}
+	defer c.Close()
+	states := make(chan raceState, 100)
+	go c.run(func(s raceState) { states <- s })
+
+	h.handle(host, raceMessage{Type: raceMsgStart})
+	for i := 0; i < raceCountdownTicks; i++ {
+		h.tick()
+	}
+	c.send(raceMessage{Type: raceMsgStride, Foot: footLeft})
+	c.send(raceMessage{Type: raceMsgStart}) // Ignored: only the host starts races
+
+	// Wait for the stride to reach the host, then for the state it causes
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s := h.tick()
+		if p, ok := s.player(c.id); ok && p.Strides == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the stride never reached the host")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for {
+		select {
+		case s := <-states:
+			p, ok := s.player(c.id)
+			if !ok || p.Strides != 1 {
+				continue // Sent before the stride arrived
+			}
+			if len(s.Players) != 2 || s.Players[0].Name != "Ana" || p.Name != "Bo" || p.Distance <= 0 || s.Phase != raceRunning {
+				t.Errorf("player got %+v, want Ana and Bo running with Bo moving", s)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("no state with the stride in it reached the player")
+		}
+	}
+}
+
+func TestRaceHostDropsPlayerThatLeaves(t *testing.T) {
+	h := newRaceHost(20, tickSpeed, colorThemes[colorThemeClassic], 1)
+	h.join("Ana")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go h.serve(l)
+
+	c, err := dialRace(l.Addr().String(), "Bo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(h.tick().Players) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("player that hung up is still in the race")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRaceHostDoesNotWaitForStalledPlayer(t *testing.T) {
+	h := newRaceHost(20, tickSpeed, colorThemes[colorThemeClassic], 1)
+	server, client := net.Pipe()
+	defer client.Close()
+	go h.serveConn(server)
+	r := bufio.NewReader(client)
+	fmt.Fprintln(client, `{"type": "hello", "name": "Bo"}`)
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bo reads nothing more, so a write to Bo never finishes
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		h.tick()
+	}
+	if elapsed := time.Since(start); elapsed > raceWriteTimeout/2 {
+		t.Errorf("20 ticks took %v with a stalled player", elapsed)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(h.tick().Players) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stalled player was never dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRaceHostDropsLongLines(t *testing.T) {
+	h := newRaceHost(20, tickSpeed, colorThemes[colorThemeClassic], 1)
+	server, client := net.Pipe()
+	defer client.Close()
+	done := make(chan struct{})
+	go func() {
+		h.serveConn(server)
+		close(done)
+	}()
+	go io.Copy(io.Discard, client)
+	fmt.Fprintln(client, `{"type": "hello", "name": "Bo"}`)
+	client.Write([]byte(`{"type": "stride", "foot": "` + strings.Repeat("x", raceMaxLine) + "\"}\n"))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("host kept reading an over-long line")
+	}
+	if n := len(h.tick().Players); n != 0 {
+		t.Errorf("%d player(s) left after an over-long line, want 0", n)
+	}
+}
+
+func TestRaceFull(t *testing.T) {
+	h := newRaceHost(20, tickSpeed, colorThemes[colorThemeClassic], 1)
+	for i := 0; i < maxRacers; i++ {
+		h.join("runner")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go h.serve(l)
+	if _, err := dialRace(l.Addr().String(), "Bo"); err == nil || err.Error() != errRaceFull.Error() {
+		t.Errorf("joining a full race: error = %v, want %v", err, errRaceFull)
+	}
+}
+
+func TestRaceView(t *testing.T) {
+	var sent []raceMessage
+	m := seededModel(defaultScene(), display{}, 1)
+	m.background = nil
+	m.startRace(&raceView{id: 2, send: func(msg raceMessage) { sent = append(sent, msg) }})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 8})
+	m = updated.(model)
+
+	s := runningRace("Ana", "Bo")
+	s.Players[1].Distance = 10 // Half way
+	s.Players[1].Strides = 1
+	updated, _ = m.Update(raceStateMsg(s))
+	m = updated.(model)
+
+	if len(m.runners) != 2 {
+		t.Fatalf("got %d runners, want one per player", len(m.runners))
+	}
+	ana, bo := m.runners[0], m.runners[1]
+	if ana.Pos.X != 0 || ana.Pos.Y+float64(len(ana.ArtFrames[0])) != 4 {
+		t.Errorf("Ana at %+v, want at the start at the bottom of the top lane", ana.Pos)
+	}
+	width := runnerBounds(&bo, Position{}).Dx()
+	if want := float64(40-width-1) / 2; bo.Pos.X != want || bo.CurrentFrameIdx != 1 {
+		t.Errorf("Bo at %+v frame %d, want half way (%v) on frame 1", bo.Pos, bo.CurrentFrameIdx, want)
+	}
+	rows := plainRows(m.frame())
+	if !strings.HasSuffix(rows[0], " 2nd Ana|") || !strings.HasSuffix(rows[4], " 1st Bo (you)|") {
+		t.Errorf("lanes start %q and %q, want names and places by the finish line", rows[0], rows[4])
+	}
+
+	for _, k := range []string{"z", "x", "r"} {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = updated.(model)
+	}
+	if len(sent) != 2 || sent[0].Foot != footLeft || sent[1].Foot != footRight {
+		t.Errorf("sent %+v, want a left and a right stride and no start from a player who isn't hosting", sent)
+	}
+
+	// Crossing the line is a result to keep, timed by the host's ticks
+	s = s.clone()
+	s.TickRate = tickSpeed / 2
+	s.Tick = raceCountdownTicks + 40
+	s.Players[1].Distance, s.Players[1].Finish = s.Length, s.Tick
+	updated, _ = m.Update(raceStateMsg(s))
+	m = updated.(model)
+	if got := m.results.Races["Bo"]; got.Seconds != 2 || got.Place != 1 || got.Runners != 2 {
+		t.Errorf("recorded %+v, want Bo 1st of 2 in 2s", got)
+	}
+	if len(m.results.Races) != 1 {
+		t.Errorf("recorded %v, want only this player's race", m.results.Races)
+	}
+}
+
+func TestRaceViewIgnoresBadStates(t *testing.T) {
+	m := seededModel(defaultScene(), display{}, 1)
+	m.startRace(&raceView{id: 1, send: func(raceMessage) {}})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 8})
+	m = updated.(model)
+	good := runningRace("Ana")
+	updated, _ = m.Update(raceStateMsg(good))
+	m = updated.(model)
+
+	for name, bad := range map[string]func(s *raceState){
+		"negative strides": func(s *raceState) { s.Players[0].Strides = -1 },
+		"no length":        func(s *raceState) { s.Length = 0 },
+		"too many players": func(s *raceState) {
+			for len(s.Players) <= maxRacers {
+				s.Players = append(s.Players, s.Players[0])
+			}
+		},
+	} {
+		s := good.clone()
+		bad(&s)
+		updated, _ = m.Update(raceStateMsg(s)) // Mustn't panic
+		m = updated.(model)
+		if len(m.runners) != 1 || math.IsNaN(m.runners[0].Pos.X) || m.race.state.Length != good.Length {
+			t.Errorf("state with %s laid out, want it ignored", name)
+		}
+	}
+}
+
+func TestRaceCountdownUsesHostTickRate(t *testing.T) {
+	m := seededModel(defaultScene(), display{}, 1)
+	m.startRace(&raceView{id: 1, send: func(raceMessage) {}})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 8})
+	m = updated.(model)
+	s := runningRace("Ana")
+	s.Phase, s.Tick, s.TickRate = raceCountdown, 0, m.tickRate/2
+	updated, _ = m.Update(raceStateMsg(s))
+	m = updated.(model)
+	rows := plainRows(m.frame())
+	if got := strings.TrimSpace(strings.Trim(rows[(len(rows)-1)/2], "|")); !strings.Contains(got, "2") || strings.Contains(got, "3") {
+		t.Errorf("countdown row %q, want 2 seconds at the host's tick rate", got)
+	}
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Race networking ---
+//
+// The host listens on TCP and players connect to it. Both ways it's JSON,
+// one message per line. A player sends hello with its name and the host
+// answers welcome with the player's ID, or error if it can't join; after
+// that the player sends its strides and the host sends the whole race state
+// after every tick. The host's own player doesn't go through the network.
+//
+// Each player's states are written by a goroutine of its own, so a slow
+// player can't hold up the race for everyone else: while one is still
+// writing, only the latest state waits to be sent. Lines from players are
+// read with a limit on their length.
+
+const (
+	defaultRaceAddr      = ":7878"
+	raceHandshakeTimeout = 5 * time.Second
+	raceWriteTimeout     = time.Second // A player this far behind is dropped
+	raceMaxLine          = 1024        // Longest line a player may send
+	raceMaxName          = 20          // Runes of a player's name kept
+)
+
+// errRaceLineTooLong is returned for a line from a player over raceMaxLine.
+var errRaceLineTooLong = errors.New("line too long")
+
+// Message types.
+const (
+	raceMsgHello   = "hello"   // Player to host: Name
+	raceMsgWelcome = "welcome" // Host to player: ID
+	raceMsgError   = "error"   // Host to player: Error; the connection is closed
+	raceMsgStride  = "stride"  // Player to host: Foot
+	raceMsgStart   = "start"   // Host's own player: start the race
+	raceMsgState   = "state"   // Host to player: State
+)
+
+// raceMessage is one line of the protocol.
+type raceMessage struct {
+	Type  string     `json:"type"`
+	Name  string     `json:"name,omitempty"`
+	ID    int        `json:"id,omitempty"`
+	Foot  raceFoot   `json:"foot,omitempty"`
+	State *raceState `json:"state,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// raceHost runs a race for the players connected to it.
+type raceHost struct {
+	mu      sync.Mutex
+	state   raceState
+	theme   colorTheme
+	rng     *rand.Rand
+	players map[int]chan raceState // Connected players' next state to send, by ID
+	conns   map[int]net.Conn
+}
+
+// newRaceHost returns a host for a race of the given length, ticking every
+// tickRate, with nobody in it.
+func newRaceHost(length float64, tickRate time.Duration, theme colorTheme, seed int64) *raceHost {
+	return &raceHost{
+		state:   raceState{Phase: raceLobby, Length: length, TickRate: tickRate},
+		theme:   theme,
+		rng:     rand.New(rand.NewSource(seed)),
+		players: map[int]chan raceState{},
+		conns:   map[int]net.Conn{},
+	}
+}
+
+// join adds a player to the race and returns its ID.
+func (h *raceHost) join(name string) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state.addPlayer(name, h.theme.jersey(h.rng))
+}
+
+// leave takes a player out of the race and hangs up on it.
+func (h *raceHost) leave(id int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dropLocked(id)
+}
+
+// dropLocked is leave with h.mu held.
+func (h *raceHost) dropLocked(id int) {
+	h.state.removePlayer(id)
+	if c, ok := h.conns[id]; ok {
+		c.Close()
+	}
+	if out, ok := h.players[id]; ok {
+		close(out)
+	}
+	delete(h.players, id)
+	delete(h.conns, id)
+}
+
+// handle applies a message from a player.
+func (h *raceHost) handle(id int, msg raceMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch msg.Type {
+	case raceMsgStride:
+		h.state.stride(id, msg.Foot)
+	case raceMsgStart:
+		h.state.start()
+	}
+}
+
+// tick advances the race by one tick, queues the new state for every
+// player and returns it. A state still waiting to be sent is replaced, as
+// each state is the whole race.
+func (h *raceHost) tick() raceState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state.step()
+	s := h.state.clone()
+	for _, out := range h.players {
+		select {
+		case <-out: // Superseded
+		default:
+		}
+		out <- s // Only tick sends, so there's room now
+	}
+	return s
+}
+
+// writeStates sends a player's states until it leaves, dropping it if a
+// write fails or takes too long.
+func (h *raceHost) writeStates(id int, c net.Conn, out <-chan raceState) {
+	enc := json.NewEncoder(c)
+	for s := range out {
+		s := s
+		c.SetWriteDeadline(time.Now().Add(raceWriteTimeout))
+		if err := enc.Encode(raceMessage{Type: raceMsgState, State: &s}); err != nil {
+			h.leave(id)
+			return
+		}
+	}
+}
+
+// run ticks every interval until stop is closed, passing each state to update.
+func (h *raceHost) run(interval time.Duration, update func(raceState), stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			update(h.tick())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// serve accepts players until the listener is closed.
+func (h *raceHost) serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go h.serveConn(c)
+	}
+}
+
+// serveConn looks after one player's connection.
+func (h *raceHost) serveConn(c net.Conn) {
+	r := bufio.NewReaderSize(c, raceMaxLine)
+	enc := json.NewEncoder(c)
+
+	c.SetDeadline(time.Now().Add(raceHandshakeTimeout))
+	hello, err := readRaceMessage(r)
+	if err != nil || hello.Type != raceMsgHello {
+		c.Close()
+		return
+	}
+	name := strings.TrimSpace(hello.Name)
+	if name == "" {
+		name = "anonymous"
+	}
+	if runes := []rune(name); len(runes) > raceMaxName {
+		name = string(runes[:raceMaxName])
+	}
+	id, err := h.join(name)
+	if err != nil {
+		enc.Encode(raceMessage{Type: raceMsgError, Error: err.Error()})
+		c.Close()
+		return
+	}
+	if err := enc.Encode(raceMessage{Type: raceMsgWelcome, ID: id}); err != nil {
+		h.leave(id)
+		c.Close()
+		return
+	}
+	c.SetDeadline(time.Time{})
+	out := make(chan raceState, 1)
+	h.mu.Lock()
+	h.players[id], h.conns[id] = out, c
+	h.mu.Unlock()
+	go h.writeStates(id, c, out)
+
+	defer h.leave(id)
+	for {
+		msg, err := readRaceMessage(r)
+		if err != nil {
+			return
+		}
+		if msg.Type == raceMsgStride { // Only the host starts races
+			h.handle(id, msg)
+		}
+	}
+}
+
+// readRaceMessage reads one line from a player, which r must buffer at
+// most raceMaxLine bytes of.
+func readRaceMessage(r *bufio.Reader) (raceMessage, error) {
+	var msg raceMessage
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return msg, errRaceLineTooLong
+	}
+	if err != nil {
+		return msg, err
+	}
+	return msg, json.Unmarshal(line, &msg)
+}
+
+// raceClient is a player's connection to a host.
+type raceClient struct {
+	conn net.Conn
+	dec  *json.Decoder
+	mu   sync.Mutex // Guards enc
+	enc  *json.Encoder
+	id   int
+}
+
+// dialRace connects to a host and joins its race.
+func dialRace(addr, name string) (*raceClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, raceHandshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &raceClient{conn: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+	conn.SetDeadline(time.Now().Add(raceHandshakeTimeout))
+	var welcome raceMessage
+	if err := c.enc.Encode(raceMessage{Type: raceMsgHello, Name: name}); err == nil {
+		err = c.dec.Decode(&welcome)
+	}
+	switch {
+	case err != nil:
+		conn.Close()
+		return nil, err
+	case welcome.Type == raceMsgError:
+		conn.Close()
+		return nil, errors.New(welcome.Error)
+	case welcome.Type != raceMsgWelcome:
+		conn.Close()
+		return nil, fmt.Errorf("unexpected %q from the host", welcome.Type)
+	}
+	conn.SetDeadline(time.Time{})
+	c.id = welcome.ID
+	return c, nil
+}
+
+// send sends a message to the host. Errors are left for run to find.
+func (c *raceClient) send(msg raceMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enc.Encode(msg)
+}
+
+// run passes each state from the host to update until the connection
+// closes, and returns why it did.
+func (c *raceClient) run(update func(raceState)) error {
+	for {
+		var msg raceMessage
+		if err := c.dec.Decode(&msg); err != nil {
+			return err
+		}
+		if msg.Type == raceMsgState && msg.State != nil {
+			update(*msg.State)
+		}
+	}
+}
+
+// Close hangs up.
+func (c *raceClient) Close() error {
+	return c.conn.Close()
+}
+
+// joinAddress guesses the address other machines can reach a listener on:
+// its port on the first non-loopback IPv4 address, if it's listening on all
+// of them.
+func joinAddress(l net.Listener) string {
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if !ok || !addr.IP.IsUnspecified() {
+		return l.Addr().String()
+	}
+	ifaces, _ := net.InterfaceAddrs()
+	for _, a := range ifaces {
+		if ip, ok := a.(*net.IPNet); ok && !ip.IP.IsLoopback() && ip.IP.To4() != nil {
+			return net.JoinHostPort(ip.IP.String(), fmt.Sprint(addr.Port))
+		}
+	}
+	return net.JoinHostPort("localhost", fmt.Sprint(addr.Port))
+}
+
+// defaultPlayerName is the name a player races under unless given one.
+func defaultPlayerName() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "player"
+}
+
+// raceModel loads the scene for a race and returns a model ready to show it.
+func raceModel(configPath, profile string) (model, error) {
+	scene, err := loadScene(configPath, profile)
+	if err != nil {
+		return model{}, err
+	}
+	art, err := loadSpriteDir(scene.SpriteDir)
+	if err != nil {
+		return model{}, err
+	}
+	setLoadedArt(art)
+	return initialModel(scene, detectDisplay(colorAuto, charsetAuto)), nil
+}
+
+// runHost implements "consolerunner host".
+func runHost(args []string) int {
+	fs := flag.NewFlagSet("host", flag.ContinueOnError)
+	listen := fs.String("listen", defaultRaceAddr, "address to accept players on")
+	name := fs.String("name", defaultPlayerName(), "your name in the race")
+	length := fs.Int("length", defaultRaceLength, "race length in cells; the finish is always at the right edge")
+	configPath := fs.String("config", "", "path to config file")
+	profile := fs.String("profile", "", "named profile from the config file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner host [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	if *length <= 0 {
+		fmt.Fprintln(os.Stderr, "--length must be greater than zero")
+		return 2
+	}
+	m, err := raceModel(*configPath, *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		return 2
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't host: %v\n", err)
+		return 1
+	}
+	defer l.Close()
+	h := newRaceHost(float64(*length), m.tickRate, m.colors(), time.Now().UnixNano())
+	id, _ := h.join(*name) // The race is empty, so there's room
+	m.startRace(&raceView{
+		id:      id,
+		host:    true,
+		address: joinAddress(l),
+		send:    func(msg raceMessage) { h.handle(id, msg) },
+	})
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	stop := make(chan struct{})
+	defer close(stop)
+	go h.serve(l)
+	go h.run(m.tickRate, func(s raceState) { p.Send(raceStateMsg(s)) }, stop)
+	final, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Alas, there's been an error: %v\n", err)
+		return 1
+	}
+	keepResults(final.(model).results, true, false)
+	return 0
+}
+
+// runJoin implements "consolerunner join".
+func runJoin(args []string) int {
+	fs := flag.NewFlagSet("join", flag.ContinueOnError)
+	name := fs.String("name", defaultPlayerName(), "your name in the race")
+	configPath := fs.String("config", "", "path to config file")
+	profile := fs.String("profile", "", "named profile from the config file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner join [flags] host:port")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	addr := fs.Arg(0)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strings.TrimPrefix(defaultRaceAddr, ":")) // Just a host
+	}
+	m, err := raceModel(*configPath, *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		return 2
+	}
+
+	c, err := dialRace(addr, *name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't join %s: %v\n", addr, err)
+		return 1
+	}
+	defer c.Close()
+	m.startRace(&raceView{id: c.id, address: addr, send: c.send})
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	go func() {
+		err := c.run(func(s raceState) { p.Send(raceStateMsg(s)) })
+		if errors.Is(err, io.EOF) {
+			err = errors.New("the host ended the race")
+		}
+		p.Send(raceEndMsg{fmt.Errorf("disconnected: %w", err)})
+	}()
+	final, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Alas, there's been an error: %v\n", err)
+		return 1
+	}
+	keepResults(final.(model).results, true, false) // Even if the host went after the race
+	if err := final.(model).err; err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// --- Scene rendering ---
+//
+// View() draws the scene into a frameBuffer of cells, one per terminal cell,
+// and then converts the buffer to a styled string. The scene is made of
+// layers (background scenery and runners) drawn back to front by depth, and
+// sprite glyphs equal to the sprite's transparent glyph are skipped, so
+// whatever is behind a runner shows through the gaps in its art.
+//
+// In the high-resolution render modes the scene is drawn the same way into a
+// buffer of pixels rather than cells, one sprite glyph per pixel, which is
+// then packed into terminal cells (see hires.go).
+
+// Default depths for the background layers. Runners are at depth 0 unless
+// configured otherwise, so negative depths are behind them and positive
+// depths in front.
+var defaultLayerDepth = map[backgroundLayer]int{
+	layerSun:       -30,
+	layerBirds:     -20,
+	layerMountains: -10,
+}
+
+// cell is one terminal cell of the frame buffer.
+type cell struct {
+	Char       rune   // 0 marks the second cell of a wide glyph
+	Color      string // lipgloss colour string; "" for the terminal default
+	Background string // Background colour; "" for the terminal default
+}
+
+// frameBuffer is a width x height grid of cells.
+type frameBuffer struct {
+	width  int
+	height int
+	cells  [][]cell
+}
+
+// newFrameBuffer returns a buffer filled with blank cells.
+func newFrameBuffer(width, height int) *frameBuffer {
+	b := &frameBuffer{width: width, height: height, cells: make([][]cell, height)}
+	for y := range b.cells {
+		b.cells[y] = make([]cell, width)
+		for x := range b.cells[y] {
+			b.cells[y][x] = cell{Char: ' '}
+		}
+	}
+	return b
+}
+
+// set writes a single-cell glyph, ignoring positions outside the buffer. A
+// wide glyph partly overwritten loses its other half too, so that no cell
+// is left half of a glyph that isn't there.
+func (b *frameBuffer) set(x, y int, char rune, color string) {
+	if x < 0 || x >= b.width || y < 0 || y >= b.height {
+		return
+	}
+	row := b.cells[y]
+	old := row[x].Char
+	if char != 0 && old == 0 && x > 0 {
+		row[x-1].Char = ' ' // Was the left half of a wide glyph
+	}
+	if old != 0 && x+1 < b.width && row[x+1].Char == 0 {
+		row[x+1].Char = ' ' // Was the right half of a wide glyph
+	}
+	row[x] = cell{Char: char, Color: color}
+}
+
+// drawLine draws a line of glyphs starting at (x, y). Glyphs equal to
+// transparent are skipped so the layers underneath show through, as are wide
+// glyphs cut in half by the edge of the buffer. colorAt returns the colour for
+// the i-th rune of the line.
+func (b *frameBuffer) drawLine(x, y int, line string, transparent rune, colorAt func(i int) string) {
+	if y < 0 || y >= b.height {
+		return
+	}
+	offset := 0
+	i := 0
+	for _, char := range line {
+		width := lipgloss.Width(string(char))
+		cut := width > 1 && (x+offset < 0 || x+offset+width > b.width)
+		if char != transparent && !cut {
+			color := colorAt(i)
+			for c := 0; c < width; c++ {
+				glyph := char
+				if c > 0 {
+					glyph = 0 // Covered by the wide glyph to its left
+				}
+				b.set(x+offset+c, y, glyph, color)
+			}
+		}
+		offset += width
+		i++
+	}
+}
+
+// render converts the buffer to a string for display d, styling each cell
+// with its colour if d has any. Neighbouring cells with the same colours are
+// styled together to keep the escape sequences down.
+func (b *frameBuffer) render(d display) string {
+	type colors struct{ fg, bg string }
+	styles := map[colors]lipgloss.Style{} // One style per colour pair in use
+	renderer := d.renderer()
+	var out, run strings.Builder
+	var runColors colors
+
+	// flush writes the pending run of same-coloured glyphs
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if d.profile == termenv.Ascii || runColors == (colors{}) {
+			out.WriteString(run.String())
+			run.Reset()
+			return
+		}
+		style, ok := styles[runColors]
+		if !ok {
+			style = renderer.NewStyle()
+			if runColors.fg != "" {
+				style = style.Foreground(lipgloss.Color(runColors.fg))
+			}
+			if runColors.bg != "" {
+				style = style.Background(lipgloss.Color(runColors.bg))
+			}
+			styles[runColors] = style
+		}
+		out.WriteString(style.Render(run.String()))
+		run.Reset()
+	}
+
+	for y, row := range b.cells {
+		for _, c := range row {
+			char := c.Char
+			if d.ascii {
+				if char == 0 {
+					char = '?' // Keep the grid: a wide glyph becomes "??", a '?' for each cell
+				}
+				char = asciiGlyph(char)
+			} else if char == 0 {
+				continue // Already rendered as part of a wide glyph
+			}
+			if key := (colors{c.Color, c.Background}); key != runColors {
+				flush()
+				runColors = key
+			}
+			run.WriteRune(char)
+		}
+		flush()
+		// Add newline unless it's the last line
+		if y < b.height-1 {
+			out.WriteString("\n")
+		}
+	}
+	return out.String()
+}
+
+// sceneLayer is one thing to draw, ordered by depth and then by order.
+type sceneLayer struct {
+	depth int
+	order float64 // Tie-break within a depth; runners use their bottom edge
+	draw  func(b *frameBuffer)
+}
+
+// buildFrame draws the whole scene (without the status line) into a new
+// buffer of terminal cells.
+func (m model) buildFrame() *frameBuffer {
+	b := newFrameBuffer(m.fieldSize())
+
+	var layers []sceneLayer
+	for _, l := range m.background {
+		layers = append(layers, sceneLayer{depth: m.layerDepth(l), draw: m.backgroundDrawer(l)})
+	}
+
+	// Positions are interpolated between the last two simulation ticks.
+	alpha := m.interpolationAlpha()
+	for i := range m.runners {
+		r := &m.runners[i]
+		pos := interpolatedPos(r, alpha)
+		jersey := m.jersey(r)
+		layers = append(layers, sceneLayer{
+			depth: r.Z,
+			order: pos.Y + float64(len(r.ArtFrames[r.CurrentFrameIdx])), // Lower on screen is nearer
+			draw:  func(b *frameBuffer) { drawRunner(b, r, pos, jersey) },
+		})
+	}
+
+	if m.game != nil {
+		layers = append(layers, sceneLayer{depth: 0, order: -1, draw: m.drawGame}) // Just behind the player
+	}
+	if m.race != nil {
+		layers = append(layers, sceneLayer{depth: 0, order: -1, draw: m.drawRace})
+	}
+
+	if m.weather != weatherClear {
+		layers = append(layers, sceneLayer{depth: weatherDepth, draw: m.drawWeather})
+	}
+
+	// Back to front; layers at the same depth and height keep their order
+	sort.SliceStable(layers, func(i, j int) bool {
+		if layers[i].depth != layers[j].depth {
+			return layers[i].depth < layers[j].depth
+		}
+		return layers[i].order < layers[j].order
+	})
+	for _, l := range layers {
+		l.draw(b)
+	}
+	cells := packPixels(b, m.render)
+	m.paintSky(cells)
+	return cells
+}
+
+// jersey returns a runner's colour for the current theme.
+func (m model) jersey(r *Runner) string {
+	if m.theme == themeDark || (m.theme == themeAuto && m.display.dark) {
+		return r.Color.Dark
+	}
+	return r.Color.Light
+}
+
+// layerDepth returns the configured depth of a background layer.
+func (m model) layerDepth(l backgroundLayer) int {
+	if depth, ok := m.layerDepths[l]; ok {
+		return depth
+	}
+	return defaultLayerDepth[l]
+}
+
+// backgroundDrawer returns the function that draws a background layer.
+func (m model) backgroundDrawer(l backgroundLayer) func(b *frameBuffer) {
+	switch l {
+	case layerSun:
+		return m.drawSun
+	case layerMountains:
+		return m.drawMountains
+	case layerBirds:
+		return m.drawBirds
+	}
+	return func(*frameBuffer) {}
+}
+
+// fixedColor is a colorAt function for single-colour art.
+func fixedColor(color string) func(int) string {
+	return func(int) string { return color }
+}
+
+// The scenery is positioned relative to the buffer rather than the terminal,
+// so it lands in the same place in every render mode.
+
+// drawSun draws the sun in the top-right corner.
+func (m model) drawSun(b *frameBuffer) {
+	sunX := b.width - lipgloss.Width(sunArt) - 2 // Position from right edge
+	sunY := 1                                    // Position from top edge
+	b.drawLine(sunX, sunY, sunArt, ' ', fixedColor(m.colors().Sun))
+}
+
+// drawMountains draws a simple repeating mountain range along the bottom.
+func (m model) drawMountains(b *frameBuffer) {
+	colors := m.colors().Mountains
+	for x := 0; x < b.width; x += lipgloss.Width(mountainArtLine1) {
+		b.drawLine(x, b.height-2, mountainArtLine1, ' ', fixedColor(colors[0]))
+		b.drawLine(x, b.height-1, mountainArtLine2, ' ', fixedColor(colors[1]))
+	}
+}
+
+// drawBirds draws a few birds scattered across the sky (very basic).
+func (m model) drawBirds(b *frameBuffer) {
+	birdPositions := []Position{{X: float64(b.width / 4), Y: 3}, {X: float64(b.width / 2), Y: 5}, {X: float64(b.width * 3 / 4), Y: 2}}
+	for _, pos := range birdPositions {
+		b.drawLine(int(pos.X), int(pos.Y), birdArt, ' ', fixedColor(m.colors().Birds))
+	}
+}
+
+// drawRunner draws a runner's current frame at pos, colouring each glyph
+// from its mask (jersey is the runner's colour for the current theme).
+func drawRunner(b *frameBuffer, r *Runner, pos Position, jersey string) {
+	frame := r.ArtFrames[r.CurrentFrameIdx]
+	for lineIdx, line := range frame {
+		keys := maskLine(r, lineIdx) // Per-glyph palette keys, nil for single-colour art
+		x, y := int(math.Floor(pos.X)), int(math.Floor(pos.Y))+lineIdx
+		b.drawLine(x, y, line, r.Transparent, func(i int) string {
+			return glyphColor(r, keys, i, jersey)
+		})
+	}
+}
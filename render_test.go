@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// plainRows returns the buffer's glyphs as one string per row, ignoring colour.
+func plainRows(b *frameBuffer) []string {
+	rows := make([]string, b.height)
+	for y, row := range b.cells {
+		var line strings.Builder
+		for _, c := range row {
+			if c.Char != 0 {
+				line.WriteRune(c.Char)
+			}
+		}
+		rows[y] = line.String()
+	}
+	return rows
+}
+
+func TestDrawLineSkipsTransparentGlyphs(t *testing.T) {
+	b := newFrameBuffer(5, 1)
+	b.drawLine(0, 0, "#####", 0, fixedColor(""))
+	b.drawLine(0, 0, "o.o o", '.', fixedColor(""))
+	if got := plainRows(b)[0]; got != "o#o o" {
+		t.Errorf("row = %q, want %q", got, "o#o o")
+	}
+}
+
+func TestDrawLineWideGlyphs(t *testing.T) {
+	b := newFrameBuffer(4, 1)
+	b.drawLine(0, 0, "日x", 0, fixedColor(""))
+	if b.cells[0][1].Char != 0 || b.cells[0][2].Char != 'x' {
+		t.Errorf("cells = %+v, want the wide glyph to cover two cells", b.cells[0])
+	}
+	if got := b.render(display{}); got != "日x " {
+		t.Errorf("render() = %q, want %q", got, "日x ")
+	}
+}
+
+// sceneModel returns a 6x4 model with no scenery and the given runners, each
+// drawn at its current position.
+func sceneModel(runners ...Runner) model {
+	for i := range runners {
+		runners[i].PrevPos = runners[i].Pos
+	}
+	m := initialModel(defaultScene(), display{})
+	m.background = nil
+	m.runners = runners
+	m.termWidth, m.termHeight = 6, 4
+	return m
+}
+
+func TestRunnerDrawOrder(t *testing.T) {
+	back := *newTestRunner(0, 0, 0, [][]string{{"AAA", "AAA"}})
+	front := *newTestRunner(1, 1, 0, [][]string{{"B B", "BBB"}})
+	back.Transparent, front.Transparent = ' ', ' '
+
+	// Lower on screen is nearer, and the gap in front's art shows back through
+	rows := plainRows(sceneModel(front, back).buildFrame())
+	if rows[0] != "AAA   " || rows[1] != "ABAB  " || rows[2] != " BBB  " {
+		t.Errorf("rows = %q, want B in front of A with A showing through", rows)
+	}
+
+	// A higher Z wins regardless of position
+	back.Z = 1
+	rows = plainRows(sceneModel(front, back).buildFrame())
+	if rows[1] != "AAAB  " {
+		t.Errorf("row 1 = %q, want A in front of B", rows[1])
+	}
+}
+
+func TestLayerDepthInFrontOfRunners(t *testing.T) {
+	r := *newTestRunner(0, 2, 0, [][]string{{"RRRRRR", "RRRRRR"}})
+	m := sceneModel(r)
+	m.background = []backgroundLayer{layerMountains}
+
+	// Behind the runner by default
+	if rows := plainRows(m.buildFrame()); rows[3] != "RRRRRR" {
+		t.Errorf("bottom row = %q, want the runner over the mountains", rows[3])
+	}
+
+	m.layerDepths = map[backgroundLayer]int{layerMountains: 1}
+	if rows := plainRows(m.buildFrame()); strings.Contains(rows[3], "RRR") {
+		t.Errorf("bottom row = %q, want the mountains over the runner", rows[3])
+	}
+}
+
+func TestHalfBlockCells(t *testing.T) {
+	tests := []struct {
+		name        string
+		top, bottom string // Pixel colours; "-" for unlit
+		want        cell
+	}{
+		{"empty", "-", "-", cell{Char: ' '}},
+		{"top only", "1", "-", cell{Char: '▀', Color: "1"}},
+		{"bottom only", "-", "2", cell{Char: '▄', Color: "2"}},
+		{"same colour", "3", "3", cell{Char: '█', Color: "3"}},
+		{"two colours", "1", "2", cell{Char: '▄', Color: "2", Background: "1"}},
+		{"default top", "", "2", cell{Char: '▀', Background: "2"}},
+		{"default bottom", "1", "", cell{Char: '▄', Background: "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := halfBlockCell(cell{Color: tt.top}, tt.top != "-", cell{Color: tt.bottom}, tt.bottom != "-")
+			if got != tt.want {
+				t.Errorf("halfBlockCell() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackPixelsBraille(t *testing.T) {
+	pixels := newFrameBuffer(4, 4)
+	pixels.set(0, 0, '#', "1")
+	pixels.set(1, 3, '#', "2")
+	pixels.set(1, 2, '#', "2")
+	b := packPixels(pixels, renderBraille)
+	if b.width != 2 || b.height != 1 {
+		t.Fatalf("packed size = %dx%d, want 2x1", b.width, b.height)
+	}
+	if want := (cell{Char: '⢡', Color: "2"}); b.cells[0][0] != want {
+		t.Errorf("cell = %+v (%q), want %+v", b.cells[0][0], b.cells[0][0].Char, want)
+	}
+	if b.cells[0][1].Char != ' ' {
+		t.Errorf("empty cell = %q, want a space", b.cells[0][1].Char)
+	}
+}
+
+func TestHalfBlockShowsSubCellMotion(t *testing.T) {
+	r := *newTestRunner(0, 0, 0, [][]string{{"#"}})
+	m := sceneModel(r)
+	m.setRenderMode(renderHalfBlock)
+
+	// Half a cell down lands on the bottom pixel of the same cell
+	m.runners[0].Pos.Y, m.runners[0].PrevPos.Y = 1, 1
+	if got := m.buildFrame().cells[0][0].Char; got != '▄' {
+		t.Errorf("cell = %q, want '▄'", got)
+	}
+}
+
+func TestSetRenderModeRescales(t *testing.T) {
+	r := *newTestRunner(3, 2, 1, nil)
+	m := sceneModel(r)
+	m.setRenderMode(renderBraille)
+	got := m.runners[0]
+	if got.Pos != (Position{X: 6, Y: 8}) || got.VelocityX != 2 {
+		t.Errorf("braille runner at %+v speed %v, want {6 8} speed 2", got.Pos, got.VelocityX)
+	}
+	m.setRenderMode(renderText)
+	if got := m.runners[0]; got.Pos != (Position{X: 3, Y: 2}) || got.VelocityX != 1 {
+		t.Errorf("text runner at %+v speed %v, want {3 2} speed 1", got.Pos, got.VelocityX)
+	}
+}
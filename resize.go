@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Terminal size handling ---
+//
+// Below minWidth x minHeight there's no room for the scene, so View() shows
+// what size is needed instead. Above it, each runner is drawn with the
+// largest of its art sizes (large, medium or tiny; see sprite.go) that fits
+// its lane: the screen height divided by the configured number of lanes. On
+// every resize the runners switch sizes as needed and are laid out again,
+// keeping their places relative to the size of the screen rather than being
+// pushed up from the bottom edge.
+
+const (
+	minWidth  = 20 // Room for the sun and a runner
+	minHeight = 5  // Room for the medium art above the mountains
+)
+
+// tooSmall reports whether the terminal is too small for the scene.
+func (m model) tooSmall() bool {
+	return m.termWidth < minWidth || m.termHeight < minHeight
+}
+
+// tooSmallView is shown instead of the scene in a terminal that's too small,
+// cut to fit however little room there is.
+func (m model) tooSmallView() string {
+	lines := []string{
+		"Terminal too small",
+		fmt.Sprintf("%dx%d, need %dx%d", m.termWidth, m.termHeight, minWidth, minHeight),
+		"Make the window bigger",
+		"or press " + m.keys.Quit.Help().Key + " to quit",
+	}
+	if len(lines) > m.termHeight {
+		lines = lines[:m.termHeight]
+	}
+	for i, line := range lines {
+		if lipgloss.Width(line) > m.termWidth {
+			line = line[:m.termWidth] // Plain ASCII, so bytes are cells
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tallestFrame returns the number of lines in a sprite's tallest frame.
+func tallestFrame(s *Sprite) int {
+	tallest := 0
+	for _, f := range s.Frames {
+		if len(f) > tallest {
+			tallest = len(f)
+		}
+	}
+	return tallest
+}
+
+// laneHeight is the height each runner's art should fit in, in the render
+// mode's pixels.
+func (m model) laneHeight() int {
+	_, height := m.fieldSize()
+	if m.lanes > 1 {
+		return height / m.lanes
+	}
+	return height
+}
+
+// spriteFor returns the art to draw a runner type with: the largest size
+// that fits a lane, or the smallest if none does. Before the terminal size
+// is known it's the large art.
+func (m model) spriteFor(rt RunnerType) *Sprite {
+	lane := m.laneHeight()
+	var s *Sprite
+	for _, size := range spriteSizes {
+		s = getSpriteVariant(rt, size)
+		if lane == 0 || tallestFrame(s) <= lane {
+			break
+		}
+	}
+	return s
+}
+
+// scalePos moves a position to the same place relative to the room a
+// runner has to move in, when that changes from oldWidth x oldRoom to
+// width x room. The room is the field height less the runner's height.
+func scalePos(p Position, oldWidth int, oldRoom float64, width int, room float64) Position {
+	p.X *= float64(width) / float64(oldWidth)
+	if oldRoom > 0 {
+		p.Y *= room / oldRoom
+	}
+	return p
+}
+
+// relayout fits the runners to a new terminal size. Each keeps its position
+// as a fraction of the room it has to move in, so a crowd spread over a big
+// window is still spread over a smaller one. oldWidth and oldHeight are the
+// previous field size, zero for the first size the model is given.
+func (m *model) relayout(oldWidth, oldHeight int) {
+	if m.world != nil {
+		m.watchWorld() // Scaled to the new size straight away
+		return
+	}
+	if m.race != nil {
+		m.applyRaceState(m.race.state)
+		for i := range m.runners {
+			m.runners[i].PrevPos = m.runners[i].Pos
+		}
+		return
+	}
+	if m.game != nil {
+		m.placePlayer() // The player keeps its art and stays on the ground
+		m.runners[0].PrevPos = m.runners[0].Pos
+		return
+	}
+	width, height := m.fieldSize()
+	for i := range m.runners {
+		r := &m.runners[i]
+		oldArtHeight := len(r.ArtFrames[r.CurrentFrameIdx])
+		if sprite := m.spriteFor(r.Type); sprite.Size != r.Size {
+			useSprite(r, sprite)
+		}
+		room := float64(height - len(r.ArtFrames[r.CurrentFrameIdx]))
+		if oldWidth > 0 && oldHeight > 0 {
+			r.Pos = scalePos(r.Pos, oldWidth, float64(oldHeight-oldArtHeight), width, room)
+		}
+		if r.Pos.Y > room {
+			r.Pos.Y = room
+		}
+		if r.Pos.Y < 0 {
+			r.Pos.Y = 0
+		}
+		r.PrevPos = r.Pos // Don't interpolate across the jump
+		if width != oldWidth {
+			r.LapStart = -1 // The lap is a different length now
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"math/rand"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 // updatePosition calculates the new position of a runner based on its velocity.
 // For now, it only updates the X coordinate. Y coordinate updates could be added later.
@@ -31,6 +35,97 @@ func updatePosition(runner *Runner, termWidth int) {
 	}
 }
 
+// updateVerticalPosition applies the runner's vertical drift and bounces it off
+// the top and bottom edges of the terminal.
+func updateVerticalPosition(runner *Runner, termHeight int) {
+	if runner == nil {
+		return
+	}
+
+	runner.Pos.Y += runner.VelocityY
+
+	artHeight := 0
+	if len(runner.ArtFrames) > 0 && runner.CurrentFrameIdx < len(runner.ArtFrames) {
+		artHeight = len(runner.ArtFrames[runner.CurrentFrameIdx])
+	}
+	if runner.Pos.Y < 0 {
+		runner.Pos.Y = 0
+		runner.VelocityY *= -1 // Reverse vertical direction
+	} else if runner.Pos.Y+float64(artHeight) > float64(termHeight) {
+		runner.Pos.Y = float64(termHeight - artHeight)
+		if runner.Pos.Y < 0 { // Prevent getting stuck if art is taller than screen
+			runner.Pos.Y = 0
+		}
+		runner.VelocityY *= -1 // Reverse vertical direction
+	}
+}
+
+// stepRunner advances a runner by one fixed simulation tick: movement in both
+// axes followed by the next animation frame. The position before the tick is
+// kept in PrevPos so the renderer can interpolate between the two.
+func stepRunner(runner *Runner, termWidth, termHeight int) {
+	if runner == nil {
+		return
+	}
+	runner.PrevPos = runner.Pos
+	updatePosition(runner, termWidth)
+	if runner.Pos.X < runner.PrevPos.X {
+		// Wrapped around to the left edge; interpolating would smear the
+		// runner across the whole screen, so snap instead.
+		runner.PrevPos.X = runner.Pos.X
+	}
+	updateVerticalPosition(runner, termHeight)
+	nextFrame(runner)
+}
+
+// interpolatedPos returns the position to draw a runner at, alpha (0..1) of the
+// way from its previous tick position to its current one.
+func interpolatedPos(runner *Runner, alpha float64) Position {
+	if alpha <= 0 {
+		return runner.PrevPos
+	}
+	if alpha >= 1 {
+		return runner.Pos
+	}
+	return Position{
+		X: runner.PrevPos.X + (runner.Pos.X-runner.PrevPos.X)*alpha,
+		Y: runner.PrevPos.Y + (runner.Pos.Y-runner.PrevPos.Y)*alpha,
+	}
+}
+
+// maskLine returns the palette keys for one line of the runner's current
+// frame, or nil if the frame has no colour mask.
+func maskLine(runner *Runner, line int) []rune {
+	if runner.CurrentFrameIdx >= len(runner.Masks) {
+		return nil
+	}
+	mask := runner.Masks[runner.CurrentFrameIdx]
+	if mask == nil {
+		return nil
+	}
+	if line >= len(mask) {
+		return []rune{} // Masked frame, but no keys for this line
+	}
+	return []rune(mask[line])
+}
+
+// glyphColor picks the colour for the col-th rune of a frame line given that
+// line's mask keys (from maskLine). jersey is the runner's own colour for the
+// current theme. An empty result means the terminal's default colour.
+func glyphColor(runner *Runner, keys []rune, col int, jersey string) string {
+	if keys == nil {
+		return jersey // No mask: the whole frame is in the runner's colour
+	}
+	if col >= len(keys) || keys[col] == ' ' {
+		return ""
+	}
+	color := runner.Palette[keys[col]]
+	if color == jerseyColor {
+		return jersey
+	}
+	return color
+}
+
 // nextFrame calculates the next animation frame index for a runner.
 // Note: This function currently modifies the runner directly.
 func nextFrame(runner *Runner) {
@@ -42,8 +137,41 @@ func nextFrame(runner *Runner) {
 
 // --- Helper functions for runner creation (can be expanded) ---
 
-// getRandomRunnerAttributes generates random attributes for a new runner.
-// This could be moved from model.go or enhanced here.
-// func getRandomRunnerAttributes(rng *rand.Rand) (RunnerType, Position, float64, lipgloss.AdaptiveColor) {
-// 	// ... implementation ...
-// }
+// newRunner creates a runner of the given type with a random starting
+// position and speed. Its colour comes from the colour theme (see
+// colorTheme.jersey). It returns false if the type has no usable art.
+func newRunner(id int, runnerType RunnerType, rng *rand.Rand) (Runner, bool) {
+	sprite := getSpriteForType(runnerType)
+	art := sprite.Frames
+	if len(art) == 0 || len(art[0]) == 0 {
+		// Skip if art is invalid/empty
+		return Runner{}, false
+	}
+	artHeight := len(art[0]) // Assuming all frames have same height
+
+	// Random initial position (ensure within typical terminal height)
+	// We'll adjust Y based on terminal height later in Update if needed
+	initialY := rng.Intn(20) + 1 // Start between line 1 and 20 initially
+	if initialY+artHeight > 24 { // Avoid starting too low on common 24-line terms
+		initialY = 24 - artHeight
+	}
+	if initialY < 0 {
+		initialY = 0
+	}
+
+	r := Runner{
+		ID:              id,
+		Type:            runnerType,
+		Pos:             Position{X: float64(rng.Intn(10)), Y: float64(initialY)}, // Cast ints to float64
+		VelocityX:       rng.Float64()*1.5 + 0.5,                                  // Random horizontal speed (0.5 to 2.0 cells/tick)
+		VelocityY:       (rng.Float64() - 0.5) * 0.2,                              // Small random vertical drift (-0.1 to +0.1 cells/tick)
+		ArtFrames:       art,
+		Masks:           sprite.Masks,
+		Palette:         sprite.Palette,
+		Transparent:     sprite.transparentGlyph(),
+		CurrentFrameIdx: 0,
+		rng:             rng, // Pass down RNG if needed per-runner
+	}
+	r.PrevPos = r.Pos // Nothing to interpolate from yet
+	return r, true
+}
@@ -152,3 +152,52 @@ func TestNextFrame(t *testing.T) {
 		})
 	}
 }
+
+func TestStepRunnerWrapSnapsPrevPos(t *testing.T) {
+	r := newTestRunner(78, 5, 3.0, [][]string{{"abc"}})
+	stepRunner(r, 80, 24)
+	if r.Pos.X != -3.0 {
+		t.Fatalf("stepRunner() Pos.X = %v, want -3", r.Pos.X)
+	}
+	if r.PrevPos.X != r.Pos.X {
+		t.Errorf("stepRunner() PrevPos.X = %v after wrap, want it snapped to %v", r.PrevPos.X, r.Pos.X)
+	}
+}
+
+func TestInterpolatedPos(t *testing.T) {
+	r := newTestRunner(10, 4, 2.0, nil)
+	r.PrevPos = Position{X: 8, Y: 2}
+
+	tests := []struct {
+		alpha float64
+		want  Position
+	}{
+		{alpha: 0, want: Position{X: 8, Y: 2}},
+		{alpha: 0.5, want: Position{X: 9, Y: 3}},
+		{alpha: 1, want: Position{X: 10, Y: 4}},
+		{alpha: 1.5, want: Position{X: 10, Y: 4}}, // Never extrapolate past the current tick
+	}
+	for _, tt := range tests {
+		if got := interpolatedPos(r, tt.alpha); got != tt.want {
+			t.Errorf("interpolatedPos(alpha=%v) = %+v, want %+v", tt.alpha, got, tt.want)
+		}
+	}
+}
+
+func TestGlyphColor(t *testing.T) {
+	r := newTestRunner(0, 0, 1.0, [][]string{{"abc"}, {"abc"}})
+	r.Masks = [][]string{{"hj "}, nil}
+	r.Palette = map[rune]string{'h': "196", 'j': jerseyColor}
+
+	keys := maskLine(r, 0)
+	for col, want := range []string{"196", "42", "", ""} {
+		if got := glyphColor(r, keys, col, "42"); got != want {
+			t.Errorf("glyphColor(col %d) = %q, want %q", col, got, want)
+		}
+	}
+
+	r.CurrentFrameIdx = 1 // Unmasked frame: everything in the jersey colour
+	if got := glyphColor(r, maskLine(r, 0), 2, "42"); got != "42" {
+		t.Errorf("glyphColor() on unmasked frame = %q, want the jersey colour", got)
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// --- High scores and lap records (consolerunner scores) ---
+//
+// Game scores and lap records are kept in scores.json under $XDG_DATA_HOME
+// (~/.local/share by default). Each session collects its own results as it
+// goes; on exit they're merged into the file and written atomically. The
+// file is locked (with scores.json.lock) while it's read, merged and
+// written, and read afresh each time, so two sessions ending at once don't
+// lose each other's results.
+//
+// A lap is once across the screen, so how long it takes depends on the
+// width of the screen as well as the runner. Lap records remember the width
+// and are compared by pace, columns per second. Only whole laps count: a
+// runner's first lap, from wherever it started, isn't timed, and neither is
+// one it was dragged during or one the window was resized during.
+//
+// Races (see race.go) are kept as each player's fastest finish. Race lengths
+// vary, so they're compared by pace too, cells per second. Every player
+// keeps their own results, so only the runner a session was racing counts.
+
+const (
+	maxHighScores   = 10               // Game scores kept
+	scoresLockWait  = 2 * time.Second  // How long to wait for another session to finish saving
+	scoresLockStale = 10 * time.Second // A lock this old was left by a session that died saving
+)
+
+// scoreBoard is the contents of scores.json, or one session's results.
+type scoreBoard struct {
+	Games   []gameScore           `json:"games,omitempty"`          // Best first, at most maxHighScores
+	Records map[string]lapRecord  `json:"records,omitempty"`        // Fastest lap by runner type
+	Bests   map[string]lapRecord  `json:"personal_bests,omitempty"` // Fastest lap by runner name
+	Races   map[string]raceRecord `json:"race_bests,omitempty"`     // Fastest race by player name
+}
+
+// gameScore is the result of one game (--game).
+type gameScore struct {
+	Score    int       `json:"score"`
+	Distance int       `json:"distance"`
+	Cleared  int       `json:"cleared"`
+	Date     time.Time `json:"date"`
+}
+
+// lapRecord is one timed lap.
+type lapRecord struct {
+	Seconds float64   `json:"seconds"`
+	Width   int       `json:"width"` // Columns across the screen
+	Type    string    `json:"type"`
+	Name    string    `json:"name,omitempty"`
+	Date    time.Time `json:"date"`
+}
+
+// pace is the lap's speed in columns per second.
+func (l lapRecord) pace() float64 {
+	return float64(l.Width) / l.Seconds
+}
+
+// raceRecord is one player's finish in a race.
+type raceRecord struct {
+	Seconds float64   `json:"seconds"`
+	Length  float64   `json:"length"` // Cells from the start to the finish
+	Place   int       `json:"place"`
+	Runners int       `json:"runners"`
+	Name    string    `json:"name"`
+	Date    time.Time `json:"date"`
+}
+
+// pace is the race's speed in cells per second.
+func (r raceRecord) pace() float64 {
+	return r.Length / r.Seconds
+}
+
+// newScoreBoard returns an empty score board.
+func newScoreBoard() scoreBoard {
+	return scoreBoard{Records: map[string]lapRecord{}, Bests: map[string]lapRecord{}, Races: map[string]raceRecord{}}
+}
+
+// empty reports whether there's nothing on the board.
+func (s scoreBoard) empty() bool {
+	return len(s.Games) == 0 && len(s.Records) == 0 && len(s.Bests) == 0 && len(s.Races) == 0
+}
+
+// addGame adds a game's score, reporting whether it made the table. Equal
+// scores keep the one set first above.
+func (s *scoreBoard) addGame(g gameScore) bool {
+	i := sort.Search(len(s.Games), func(i int) bool { return s.Games[i].Score < g.Score })
+	if i >= maxHighScores {
+		return false
+	}
+	s.Games = append(s.Games, gameScore{})
+	copy(s.Games[i+1:], s.Games[i:])
+	s.Games[i] = g
+	if len(s.Games) > maxHighScores {
+		s.Games = s.Games[:maxHighScores]
+	}
+	return true
+}
+
+// addLap adds a timed lap, reporting whether it's a record for its runner
+// type or a personal best for a named runner.
+func (s *scoreBoard) addLap(l lapRecord) bool {
+	if l.Seconds <= 0 || l.Width <= 0 {
+		return false
+	}
+	improved := false
+	if old, ok := s.Records[l.Type]; !ok || l.pace() > old.pace() {
+		s.Records[l.Type] = l
+		improved = true
+	}
+	if l.Name != "" {
+		if old, ok := s.Bests[l.Name]; !ok || l.pace() > old.pace() {
+			s.Bests[l.Name] = l
+			improved = true
+		}
+	}
+	return improved
+}
+
+// addRace adds a player's finish, reporting whether it's their best.
+func (s *scoreBoard) addRace(r raceRecord) bool {
+	if r.Seconds <= 0 || r.Length <= 0 {
+		return false
+	}
+	if old, ok := s.Races[r.Name]; ok && r.pace() <= old.pace() {
+		return false
+	}
+	s.Races[r.Name] = r
+	return true
+}
+
+// merge adds another board's results to s, reporting whether any of them
+// made the table or set a record.
+func (s *scoreBoard) merge(o scoreBoard) bool {
+	improved := false
+	for _, g := range o.Games {
+		improved = s.addGame(g) || improved
+	}
+	for _, laps := range []map[string]lapRecord{o.Records, o.Bests} {
+		for _, l := range laps {
+			improved = s.addLap(l) || improved
+		}
+	}
+	for _, r := range o.Races {
+		improved = s.addRace(r) || improved
+	}
+	return improved
+}
+
+// timeLap is called as a runner starts a new lap, and records the one it
+// has just finished if it was timed from the start.
+func (m *model) timeLap(r *Runner) {
+	if r.Laps > 1 && r.LapStart >= 0 {
+		m.results.addLap(lapRecord{
+			Seconds: (time.Duration(m.ticks-r.LapStart) * m.tickRate).Seconds(),
+			Width:   m.termWidth,
+			Type:    r.Type.String(),
+			Name:    r.Name,
+			Date:    time.Now(),
+		})
+	}
+	r.LapStart = m.ticks
+}
+
+// recordRace records this player's finish in a race.
+func (m *model) recordRace(s raceState, p racePlayer) {
+	place, runners := 0, 0
+	for i, q := range s.standings() {
+		if q.ID == p.ID {
+			place = i + 1
+		}
+		if !q.Waiting {
+			runners++
+		}
+	}
+	m.results.addRace(raceRecord{
+		Seconds: s.elapsed(p.Finish - raceCountdownTicks).Seconds(),
+		Length:  s.Length,
+		Place:   place,
+		Runners: runners,
+		Name:    p.Name,
+		Date:    time.Now(),
+	})
+}
+
+// defaultDataDir returns the per-user directory for data kept between
+// sessions, or "" if there's no home directory to keep it in.
+func defaultDataDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if !filepath.IsAbs(dir) { // Unset, or relative, which the spec says to ignore
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "consolerunner")
+}
+
+// defaultScoresPath returns the per-user scores file location, or "" if
+// there's no home directory to keep it in.
+func defaultScoresPath() string {
+	dir := defaultDataDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "scores.json")
+}
+
+// loadScores reads a scores file. A missing file is an empty board.
+func loadScores(path string) (scoreBoard, error) {
+	s := newScoreBoard()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Records == nil {
+		s.Records = map[string]lapRecord{}
+	}
+	if s.Bests == nil {
+		s.Bests = map[string]lapRecord{}
+	}
+	if s.Races == nil {
+		s.Races = map[string]raceRecord{}
+	}
+	return s, nil
+}
+
+// saveScores merges a session's results into the scores file, returning
+// the merged board and whether the session made the table or set a record.
+// A file that can't be read is left alone rather than replaced.
+func saveScores(path string, session scoreBoard) (scoreBoard, bool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return scoreBoard{}, false, err
+	}
+	unlock, err := lockScores(path)
+	if err != nil {
+		return scoreBoard{}, false, err
+	}
+	defer unlock()
+
+	s, err := loadScores(path)
+	if err != nil {
+		return s, false, err
+	}
+	improved := s.merge(session)
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return s, improved, err
+	}
+	return s, improved, writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// lockScores takes the lock on the scores file at path, waiting a little
+// for another session to finish with it, and returns the function that
+// lets it go.
+func lockScores(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(scoresLockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > scoresLockStale {
+			os.Remove(lock) // Nobody is coming back for it
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another session (remove %s if not)", path, lock)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// keepResults saves a session's results and shows the table if they got
+// onto it, unless quiet, or always if asked.
+func keepResults(results scoreBoard, always, quiet bool) {
+	path := defaultScoresPath()
+	if path == "" || results.empty() {
+		return
+	}
+	board, improved, err := saveScores(path, results)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't save scores: %v\n", err)
+	} else if always || improved && !quiet {
+		writeScores(os.Stdout, board)
+	}
+}
+
+// writeScores prints the high-score table and lap records.
+func writeScores(w io.Writer, s scoreBoard) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if s.empty() {
+		fmt.Fprintln(tw, "No scores yet: play with --game, race, or watch some laps")
+	}
+	if len(s.Games) > 0 {
+		fmt.Fprintln(tw, "High scores")
+		for i, g := range s.Games {
+			fmt.Fprintf(tw, "%3d.\t%d\tdistance %d, %d cleared\t%s\n", i+1, g.Score, g.Distance, g.Cleared, g.Date.Format("2006-01-02"))
+		}
+	}
+	writeLaps := func(title string, laps map[string]lapRecord) {
+		if len(laps) == 0 {
+			return
+		}
+		keys := make([]string, 0, len(laps))
+		for k := range laps {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(tw, title)
+		for _, k := range keys {
+			l := laps[k]
+			who := l.Type
+			if l.Name != "" {
+				who = l.Name + " (" + l.Type + ")"
+			}
+			fmt.Fprintf(tw, "  %s\t%.1fs over %d columns\t%.1f/s\t%s\n", who, l.Seconds, l.Width, l.pace(), l.Date.Format("2006-01-02"))
+		}
+	}
+	writeLaps("Fastest laps", s.Records)
+	writeLaps("Personal bests", s.Bests)
+	if len(s.Races) > 0 {
+		names := make([]string, 0, len(s.Races))
+		for name := range s.Races {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(tw, "Fastest races")
+		for _, name := range names {
+			r := s.Races[name]
+			fmt.Fprintf(tw, "  %s\t%.1fs over %.0f cells\t%s of %d\t%s\n", name, r.Seconds, r.Length, ordinal(r.Place), r.Runners, r.Date.Format("2006-01-02"))
+		}
+	}
+	return tw.Flush()
+}
+
+// runScores implements "consolerunner scores".
+func runScores(args []string) int {
+	fs := flag.NewFlagSet("scores", flag.ContinueOnError)
+	path := fs.String("file", defaultScoresPath(), "scores file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner scores [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	s, err := loadScores(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read scores: %v\n", err)
+		return 1
+	}
+	if err := writeScores(os.Stdout, s); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't write scores: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddGameKeepsBestScores(t *testing.T) {
+	s := newScoreBoard()
+	for i := 1; i <= maxHighScores+2; i++ {
+		s.addGame(gameScore{Score: i * 10})
+	}
+	if len(s.Games) != maxHighScores || s.Games[0].Score != (maxHighScores+2)*10 || s.Games[maxHighScores-1].Score != 30 {
+		t.Errorf("kept %+v, want the %d best, best first", s.Games, maxHighScores)
+	}
+	if s.addGame(gameScore{Score: 5}) {
+		t.Error("a score below the whole table made it")
+	}
+	if !s.addGame(gameScore{Score: 60, Cleared: 1}) || s.Games[7].Cleared != 1 || s.Games[6].Score != 60 {
+		t.Errorf("tied score went in at the wrong place: %+v", s.Games)
+	}
+}
+
+func TestAddLapComparesPace(t *testing.T) {
+	s := newScoreBoard()
+	if !s.addLap(lapRecord{Seconds: 10, Width: 80, Type: "Jogger", Name: "Ana"}) {
+		t.Error("first lap wasn't a record")
+	}
+	// Slower in time but on a wider screen, so faster
+	if !s.addLap(lapRecord{Seconds: 12, Width: 120, Type: "Jogger"}) {
+		t.Error("faster pace wasn't a record")
+	}
+	if s.addLap(lapRecord{Seconds: 9, Width: 60, Type: "Jogger"}) {
+		t.Error("slower pace was a record")
+	}
+	if s.Records["Jogger"].Width != 120 || s.Bests["Ana"].Seconds != 10 {
+		t.Errorf("records %+v, bests %+v", s.Records, s.Bests)
+	}
+}
+
+func TestAddRaceComparesPace(t *testing.T) {
+	s := newScoreBoard()
+	if !s.addRace(raceRecord{Seconds: 20, Length: 200, Name: "Ana"}) {
+		t.Error("first race wasn't a best")
+	}
+	if s.addRace(raceRecord{Seconds: 15, Length: 100, Name: "Ana"}) {
+		t.Error("quicker but slower-paced race was a best")
+	}
+	if !s.addRace(raceRecord{Seconds: 25, Length: 300, Name: "Ana"}) || s.Races["Ana"].Length != 300 {
+		t.Errorf("faster pace wasn't kept: %+v", s.Races)
+	}
+}
+
+func TestTimeLapSkipsPartialLaps(t *testing.T) {
+	m := sceneModel(*newTestRunner(0, 0, 1, [][]string{{"A"}}))
+	m.runners[0].Name = "Ana"
+	m.termWidth = 10
+	for i := 0; i < 100; i++ {
+		m.step()
+	}
+	r := m.results.Bests["Ana"]
+	// From just off the left edge (-1) to past the right one (11)
+	if want := (12 * tickSpeed).Seconds(); r.Seconds != want || r.Width != 10 {
+		t.Errorf("best lap %+v, want %vs over 10 columns", r, want)
+	}
+
+	// Dragging a runner spoils the lap it's on
+	m = sceneModel(*newTestRunner(0, 0, 1, [][]string{{"A"}}))
+	m.termWidth = 10
+	for i := 0; i < 15; i++ {
+		m.step()
+	}
+	m.runners[0].LapStart = -1
+	for i := 0; i < 8; i++ {
+		m.step()
+	}
+	if !m.results.empty() {
+		t.Errorf("timed a lap the runner was dragged during: %+v", m.results)
+	}
+}
+
+func TestSaveScoresMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "consolerunner", "scores.json")
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := newScoreBoard()
+	first.addGame(gameScore{Score: 100, Distance: 80, Cleared: 2, Date: date})
+	first.addLap(lapRecord{Seconds: 8, Width: 80, Type: "Jogger", Name: "Ana", Date: date})
+	if _, improved, err := saveScores(path, first); err != nil || !improved {
+		t.Fatalf("saveScores() = %v, %v", improved, err)
+	}
+
+	second := newScoreBoard()
+	second.addGame(gameScore{Score: 50, Date: date})
+	second.addLap(lapRecord{Seconds: 9, Width: 80, Type: "Jogger", Date: date})
+	second.addRace(raceRecord{Seconds: 20, Length: 200, Place: 1, Runners: 3, Name: "Bo", Date: date})
+	board, _, err := saveScores(path, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadScores(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, board) {
+		t.Errorf("file has %+v, want %+v", loaded, board)
+	}
+	if len(loaded.Games) != 2 || loaded.Records["Jogger"].Seconds != 8 || loaded.Races["Bo"].Seconds != 20 {
+		t.Errorf("merged board %+v, want both games, the faster lap and the race", loaded)
+	}
+
+	var out strings.Builder
+	if err := writeScores(&out, loaded); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"  1.  100  distance 80, 2 cleared", "Ana (Jogger)  8.0s over 80 columns", "20.0s over 200 cells", "1st of 3"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSaveScoresConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.json")
+	var wg sync.WaitGroup
+	errs := make(chan error, maxHighScores)
+	for i := 1; i <= maxHighScores; i++ {
+		session := newScoreBoard()
+		session.addGame(gameScore{Score: i})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := saveScores(path, session)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := loadScores(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Games) != maxHighScores {
+		t.Errorf("kept %d of %d sessions' scores, want them all", len(loaded.Games), maxHighScores)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestSaveScoresBreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.json")
+	if err := os.WriteFile(path+".lock", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * scoresLockStale)
+	os.Chtimes(path+".lock", old, old)
+	session := newScoreBoard()
+	session.addGame(gameScore{Score: 1})
+	if _, _, err := saveScores(path, session); err != nil {
+		t.Errorf("saveScores() with a stale lock: %v", err)
+	}
+}
+
+func TestSaveScoresKeepsUnreadableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	session := newScoreBoard()
+	session.addGame(gameScore{Score: 1})
+	if _, _, err := saveScores(path, session); err == nil {
+		t.Error("saveScores() over a corrupt file succeeded")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "not json" {
+		t.Errorf("corrupt file was replaced with %q", data)
+	}
+}
+
+func TestDefaultScoresPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+	if got, want := defaultScoresPath(), filepath.Join("/data", "consolerunner", "scores.json"); got != want {
+		t.Errorf("defaultScoresPath() = %q, want %q", got, want)
+	}
+	t.Setenv("XDG_DATA_HOME", "relative")
+	t.Setenv("HOME", "/home/ana")
+	if got, want := defaultScoresPath(), filepath.Join("/home/ana", ".local", "share", "consolerunner", "scores.json"); got != want {
+		t.Errorf("with a relative XDG_DATA_HOME, defaultScoresPath() = %q, want %q", got, want)
+	}
+}
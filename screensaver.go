@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// --- Screensaver mode (--screensaver) ---
+//
+// In screensaver mode any key press or mouse movement quits, and the scene
+// slowly changes so it never looks static: the crowd grows and shrinks, some
+// runners that leave on the right come back as a different type, and the
+// weather changes every few minutes. With --idle it installs itself as tmux's
+// lock command instead of running, so tmux starts it after that much idle
+// time.
+
+const (
+	crowdEvery   = 300  // Ticks between changes to the crowd size (30s at the default rate)
+	weatherEvery = 1200 // Ticks between weather changes (2 minutes at the default rate)
+	enterChance  = 50   // 1 in enterChance ticks a runner joins a crowd that's too small
+	swapChance   = 3    // 1 in swapChance runners leaving is replaced by a new one
+	weatherDepth = 10   // In front of the runners and the default scenery
+)
+
+// weatherKind is the current weather; the zero value is clear skies.
+type weatherKind int
+
+const (
+	weatherClear weatherKind = iota
+	weatherRain
+	weatherSnow
+)
+
+// weatherStyle describes how a kind of weather is drawn.
+type weatherStyle struct {
+	glyphs  []rune // Particle glyphs, picked per column
+	color   string
+	speed   float64 // Rows per tick
+	spacing int     // One column in spacing has particles
+}
+
+var weatherStyles = map[weatherKind]weatherStyle{
+	weatherRain: {glyphs: []rune{'|', '\''}, color: "39", speed: 1.5, spacing: 3},
+	weatherSnow: {glyphs: []rune{'*', '.'}, color: "255", speed: 0.3, spacing: 4},
+}
+
+// startScreensaver switches the model to screensaver mode.
+func (m *model) startScreensaver() {
+	m.screensaver = true
+	m.crowdTarget = len(m.runners)
+}
+
+// evolve applies the slow scene changes of screensaver mode after a tick.
+// wrapped lists the runners that just ran off the right edge.
+func (m *model) evolve(wrapped []int) {
+	if m.ticks%crowdEvery == 0 {
+		m.crowdTarget = m.rng.Intn(maxRunners-minRunners+1) + minRunners
+	}
+	if m.ticks%weatherEvery == 0 {
+		// Clear skies are as likely as any weather
+		kinds := []weatherKind{weatherClear, weatherClear, weatherRain, weatherSnow}
+		m.weather = kinds[m.rng.Intn(len(kinds))]
+	}
+
+	// Runners leave when they reach the right edge, in reverse so the
+	// indexes stay valid as runners are removed
+	for i := len(wrapped) - 1; i >= 0; i-- {
+		idx := wrapped[i]
+		switch {
+		case len(m.runners) > m.crowdTarget:
+			m.runners = append(m.runners[:idx], m.runners[idx+1:]...)
+		case m.rng.Intn(swapChance) == 0:
+			if r, ok := m.spawnRunner(); ok {
+				m.runners[idx] = r
+			}
+		}
+	}
+	if len(m.runners) < m.crowdTarget && m.rng.Intn(enterChance) == 0 {
+		if r, ok := m.spawnRunner(); ok {
+			m.runners = append(m.runners, r)
+		}
+	}
+}
+
+// spawnRunner creates a random runner just off the left edge, ready to run on.
+func (m *model) spawnRunner() (Runner, bool) {
+	id := 0
+	for _, r := range m.runners {
+		if r.ID >= id {
+			id = r.ID + 1
+		}
+	}
+	r, ok := newRunner(id, RunnerType(m.rng.Intn(int(TenKRunner+1))), m.rng)
+	if !ok {
+		return Runner{}, false
+	}
+	r.Color = m.colors().jersey(m.rng)
+	useSprite(&r, m.spriteFor(r.Type)) // The size that fits a lane
+
+	// newRunner works in cells; convert to the render mode's pixels
+	px, py := m.render.cellPixels()
+	r.VelocityX *= float64(px)
+	r.VelocityY *= float64(py)
+	_, height := m.fieldSize()
+	r.Pos.X = float64(-lipgloss.Width(r.ArtFrames[0][0]))
+	r.Pos.Y = 0
+	if room := height - len(r.ArtFrames[0]); room > 0 {
+		r.Pos.Y = float64(m.rng.Intn(room))
+	}
+	r.PrevPos = r.Pos
+	return r, true
+}
+
+// drawWeather draws falling particles. Each particle column falls from its
+// own starting height, so the pattern doesn't look like a moving grid.
+func (m model) drawWeather(b *frameBuffer) {
+	style, ok := weatherStyles[m.weather]
+	if !ok || b.height == 0 {
+		return
+	}
+	_, py := m.render.cellPixels()
+	t := float64(m.ticks) + m.interpolationAlpha()
+	for x := 0; x < b.width; x++ {
+		h := columnHash(x)
+		if h%uint32(style.spacing) != 0 {
+			continue
+		}
+		y := math.Mod(float64(h>>8)+t*style.speed*float64(py), float64(b.height))
+		glyph := style.glyphs[(h>>4)%uint32(len(style.glyphs))]
+		b.set(x, int(y), glyph, style.color)
+	}
+}
+
+// columnHash scrambles a column number into a stable pseudo-random value.
+func columnHash(x int) uint32 {
+	h := uint32(x)*2654435761 + 0x9e3779b9
+	h ^= h >> 15
+	return h * 2246822519
+}
+
+// installTmuxLock sets tmux's lock command to run this program as a
+// screensaver (with the other flags it was given) after idle time without
+// input.
+func installTmuxLock(idle time.Duration) error {
+	if os.Getenv("TMUX") == "" {
+		return fmt.Errorf("--idle only works inside tmux")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	command := []string{shellQuote(exe)}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "idle" {
+			command = append(command, shellQuote("--"+f.Name+"="+f.Value.String()))
+		}
+	})
+
+	secs := int(math.Ceil(idle.Seconds()))
+	for _, args := range [][]string{
+		{"set-option", "-g", "lock-command", strings.Join(command, " ")},
+		{"set-option", "-g", "lock-after-time", strconv.Itoa(secs)},
+	} {
+		if out, err := exec.Command("tmux", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("tmux %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes s for sh, which tmux runs the lock command with.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newScreensaverModel builds a screensaver model with n runners on a
+// 40x12 terminal.
+func newScreensaverModel(n int) model {
+	m := initialModel(defaultScene(), display{})
+	m.rng = rand.New(rand.NewSource(1))
+	m.runners = nil
+	for i := 0; i < n; i++ {
+		m.runners = append(m.runners, *newTestRunner(0, 2, 1, [][]string{{"x"}}))
+	}
+	m.termWidth, m.termHeight = 40, 12
+	m.startScreensaver()
+	return m
+}
+
+func TestScreensaverQuitsOnAnyInput(t *testing.T) {
+	for _, msg := range []tea.Msg{
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}},
+		tea.KeyMsg{Type: tea.KeySpace},
+		tea.MouseMsg{X: 3, Y: 4, Action: tea.MouseActionMotion},
+	} {
+		_, cmd := newScreensaverModel(1).Update(msg)
+		if cmd == nil {
+			t.Errorf("Update(%v) returned no command, want tea.Quit", msg)
+			continue
+		}
+		if _, ok := cmd().(tea.QuitMsg); !ok {
+			t.Errorf("Update(%v) didn't quit", msg)
+		}
+	}
+
+	// Outside screensaver mode only the quit keys quit
+	m := initialModel(defaultScene(), display{})
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}}); cmd != nil {
+		t.Errorf("normal mode quit on 'a'")
+	}
+}
+
+func TestScreensaverCrowdChanges(t *testing.T) {
+	m := newScreensaverModel(minRunners)
+	sizes := map[int]bool{}
+	for i := 0; i < 20*crowdEvery; i++ {
+		m.step()
+		sizes[len(m.runners)] = true
+		if len(m.runners) > maxRunners {
+			t.Fatalf("crowd grew to %d runners, want at most %d", len(m.runners), maxRunners)
+		}
+	}
+	if len(sizes) < 3 {
+		t.Errorf("crowd sizes seen = %v, want the crowd to grow and shrink", sizes)
+	}
+}
+
+func TestScreensaverWeather(t *testing.T) {
+	m := newScreensaverModel(0)
+	m.background = nil
+	clear := m.View()
+
+	m.weather = weatherSnow
+	if snowy := m.View(); snowy == clear || !strings.Contains(snowy, "*") && !strings.Contains(snowy, ".") {
+		t.Errorf("snowy view shows no snow:\n%s", snowy)
+	}
+
+	seen := map[weatherKind]bool{}
+	for i := 0; i < 20*weatherEvery; i++ {
+		m.step()
+		seen[m.weather] = true
+	}
+	if len(seen) != len(weatherStyles)+1 {
+		t.Errorf("weather seen = %v, want every kind", seen)
+	}
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/ssh"
+)
+
+// --- Serving the scene over SSH (consolerunner serve) ---
+//
+// serve --ssh serves the scene to SSH clients, and serve --http to browsers
+// (see web.go); it can do both at once. Anyone can ssh in and watch:
+// there's no authentication, as there's nothing to get at but the runners.
+// Each session gets its own model, sized to the client's terminal and drawn
+// for what it says it can show (TERM, COLORTERM and the locale, sent as
+// environment variables). With --shared, every session watches one world
+// run by the server instead, each scaled to its own terminal size, so
+// everyone sees the same runners.
+//
+// Browsers always watch the shared world, the same one as SSH sessions
+// with --shared.
+//
+// As anyone can connect, each listener takes at most --max-conns clients
+// at once, each SSH client gets a few sessions at most, and clients that
+// don't finish their handshake in time are hung up on.
+//
+// The host key is generated on first use and kept in the data directory
+// (see scores.go), so clients don't see it change between runs.
+
+const (
+	worldWidth       = 80 // Size of the shared world, in cells; sessions scale it to fit
+	worldHeight      = 24
+	defaultMaxConns  = 64 // Clients each listener takes at once
+	maxSSHSessions   = 4  // Sessions each SSH client can have open at once
+	maxSSHEnv        = 32 // Environment variables kept for each session
+	handshakeTimeout = 10 * time.Second
+
+	// maxViewSize is the most rows or columns a session or browser can ask
+	// for, so a client can't make the server draw an enormous scene.
+	maxViewSize = 500
+)
+
+// sharedWorld is a scene run by the server for every session to watch.
+type sharedWorld struct {
+	mu sync.Mutex
+	m  model
+}
+
+// newSharedWorld sets up a world for a scene.
+func newSharedWorld(scene Scene) *sharedWorld {
+	m := initialModel(scene, display{})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: worldWidth, Height: worldHeight})
+	return &sharedWorld{m: updated.(model)}
+}
+
+// run advances the world in real time until stop is closed.
+func (w *sharedWorld) run(stop <-chan struct{}) {
+	w.mu.Lock()
+	ticker := time.NewTicker(w.m.frameDelay)
+	w.mu.Unlock()
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.mu.Lock()
+			updated, _ := w.m.Update(tickMsg(now))
+			w.m = updated.(model)
+			w.mu.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// snapshot returns a copy of the world that's safe to read while it goes on.
+func (w *sharedWorld) snapshot() model {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	m := w.m
+	m.runners = append([]Runner(nil), w.m.runners...)
+	return m
+}
+
+// watchWorld replaces the model's runners with the shared world's, scaled
+// from the world's size to this terminal's and drawn with the art that fits.
+func (m *model) watchWorld() {
+	world := m.world.snapshot()
+	ww, wh := world.fieldSize()
+	width, height := m.fieldSize()
+	for i := range world.runners {
+		r := &world.runners[i]
+		worldRoom := float64(wh - len(r.ArtFrames[r.CurrentFrameIdx]))
+		if sprite := m.spriteFor(r.Type); sprite.Size != r.Size {
+			useSprite(r, sprite)
+		}
+		room := float64(height - len(r.ArtFrames[r.CurrentFrameIdx]))
+		r.Pos = scalePos(r.Pos, ww, worldRoom, width, room)
+		r.PrevPos = scalePos(r.PrevPos, ww, worldRoom, width, room)
+	}
+	m.runners = world.runners
+	m.ticks, m.accumulator = world.ticks, world.accumulator
+}
+
+// limitListener accepts at most n connections at once, hanging up on any
+// more straight away rather than leaving them waiting.
+type limitListener struct {
+	net.Listener
+	slots chan struct{}
+}
+
+// newLimitListener wraps l to take at most n connections at once.
+func newLimitListener(l net.Listener, n int) *limitListener {
+	return &limitListener{Listener: l, slots: make(chan struct{}, n)}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case l.slots <- struct{}{}:
+			return &limitConn{Conn: c, release: func() { <-l.slots }}, nil
+		default:
+			c.Close() // Full
+		}
+	}
+}
+
+// limitConn gives its listener's slot back when it's closed.
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// sshEnv is the environment a client sent for its session.
+type sshEnv map[string]string
+
+func (e sshEnv) Getenv(key string) string { return e[key] }
+
+func (e sshEnv) Environ() []string {
+	var env []string
+	for k, v := range e {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// sshServer serves the scene to SSH clients.
+type sshServer struct {
+	config *ssh.ServerConfig
+	scene  Scene
+	world  *sharedWorld // nil unless every session watches the same world
+}
+
+// newSSHServer returns a server for a scene, identified by signer.
+func newSSHServer(scene Scene, signer ssh.Signer, world *sharedWorld) *sshServer {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+	return &sshServer{config: config, scene: scene, world: world}
+}
+
+// serve accepts connections until the listener is closed.
+func (s *sshServer) serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleConn(c)
+	}
+}
+
+// handleConn looks after one client connection and its sessions.
+func (s *sshServer) handleConn(c net.Conn) {
+	c.SetDeadline(time.Now().Add(handshakeTimeout))
+	conn, chans, reqs, err := ssh.NewServerConn(c, s.config)
+	if err != nil {
+		c.Close()
+		return
+	}
+	c.SetDeadline(time.Time{})
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+	sessions := make(chan struct{}, maxSSHSessions)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		select {
+		case sessions <- struct{}{}:
+		default:
+			nc.Reject(ssh.ResourceShortage, "too many sessions")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			<-sessions
+			continue
+		}
+		go func() {
+			defer func() { <-sessions }()
+			s.handleSession(ch, requests)
+		}()
+	}
+}
+
+// handleSession runs the scene for one session once the client asks for a
+// shell, passing it window size changes, until either side quits.
+func (s *sshServer) handleSession(ch ssh.Channel, requests <-chan *ssh.Request) {
+	env := sshEnv{}
+	var width, height int
+	var p *tea.Program
+	done := make(chan struct{})
+
+	for req := range requests {
+		ok := false
+		switch req.Type {
+		case "env":
+			var kv struct{ Name, Value string }
+			if ssh.Unmarshal(req.Payload, &kv) == nil && len(env) < maxSSHEnv {
+				env[kv.Name], ok = kv.Value, true
+			}
+		case "pty-req":
+			var pty struct {
+				Term               string
+				Cols, Rows, PW, PH uint32
+				Modes              string
+			}
+			if ssh.Unmarshal(req.Payload, &pty) == nil && viewSizeOK(int(pty.Cols), int(pty.Rows)) {
+				env["TERM"], width, height, ok = pty.Term, int(pty.Cols), int(pty.Rows), true
+			}
+		case "window-change":
+			var size struct{ Cols, Rows, PW, PH uint32 }
+			if ssh.Unmarshal(req.Payload, &size) == nil && viewSizeOK(int(size.Cols), int(size.Rows)) && p != nil {
+				p.Send(tea.WindowSizeMsg{Width: int(size.Cols), Height: int(size.Rows)})
+			}
+		case "shell":
+			if p != nil {
+				break
+			}
+			ok = true
+			if width == 0 || height == 0 {
+				break // No pty-req; explained below, once the client is listening
+			}
+			p = s.program(ch, env)
+			go func() {
+				defer close(done)
+				_, err := p.Run()
+				if err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+					exitSession(ch, 1)
+				} else {
+					exitSession(ch, 0)
+				}
+			}()
+			go p.Send(tea.WindowSizeMsg{Width: width, Height: height})
+		}
+		if req.WantReply {
+			req.Reply(ok, nil)
+		}
+		if req.Type == "shell" && p == nil {
+			fmt.Fprint(ch.Stderr(), "consolerunner needs a terminal: try ssh -t\r\n")
+			exitSession(ch, 1)
+		}
+	}
+
+	// The client has gone
+	if p != nil {
+		p.Kill()
+		<-done
+	}
+	ch.Close()
+}
+
+// viewSizeOK reports whether a client's terminal size is one worth drawing
+// for: not empty, and no more than maxViewSize either way.
+func viewSizeOK(cols, rows int) bool {
+	return cols >= 1 && rows >= 1 && cols <= maxViewSize && rows <= maxViewSize
+}
+
+// exitSession tells the client the session is over and how it went.
+func exitSession(ch ssh.Channel, status uint32) {
+	ch.SendRequest("exit-status", false, ssh.Marshal(&struct{ Status uint32 }{status}))
+	ch.Close()
+}
+
+// program returns the scene for a session, drawn on the session's channel.
+func (s *sshServer) program(ch ssh.Channel, env sshEnv) *tea.Program {
+	m := initialModel(s.scene, envDisplay(env))
+	opts := []tea.ProgramOption{
+		tea.WithInput(ch), tea.WithOutput(ch), tea.WithAltScreen(),
+		tea.WithoutSignalHandler(), // Signals are for the server, not each session
+	}
+	if s.world != nil {
+		m.world = s.world
+	} else {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	return tea.NewProgram(m, opts...)
+}
+
+// defaultHostKeyPath returns where the server's host key is kept.
+func defaultHostKeyPath() string {
+	dir := defaultDataDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "ssh_host_ed25519_key")
+}
+
+// loadHostKey reads the server's private key, generating and saving a new
+// one if there isn't one yet.
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "consolerunner")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(path, data, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// runServe implements "consolerunner serve".
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	sshAddr := fs.String("ssh", "", "serve the scene to SSH clients on this address, e.g. :2222")
+	httpAddr := fs.String("http", "", "serve a page streaming the scene to browsers on this address, e.g. :8080")
+	shared := fs.Bool("shared", false, "SSH sessions all watch the same runners, as browsers do, instead of a scene each")
+	hostKey := fs.String("host-key", defaultHostKeyPath(), "SSH host key, generated if it doesn't exist")
+	configPath := fs.String("config", "", "path to config file")
+	profile := fs.String("profile", "", "named profile from the config file")
+	maxConns := fs.Int("max-conns", defaultMaxConns, "clients each listener takes at once")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner serve [--ssh ADDR] [--http ADDR] [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 || (*sshAddr == "" && *httpAddr == "") {
+		fs.Usage()
+		return 2
+	}
+	if *maxConns < 1 {
+		fmt.Fprintln(os.Stderr, "--max-conns must be at least 1")
+		return 2
+	}
+	if *sshAddr != "" && *hostKey == "" {
+		fmt.Fprintln(os.Stderr, "No home directory to keep the host key in: give --host-key")
+		return 2
+	}
+
+	scene, err := loadScene(*configPath, *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		return 2
+	}
+	art, err := loadSpriteDir(scene.SpriteDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Sprite error: %v\n", err)
+		return 2
+	}
+	setLoadedArt(art)
+
+	var world *sharedWorld
+	if *shared || *httpAddr != "" {
+		world = newSharedWorld(scene)
+		stop := make(chan struct{})
+		defer close(stop)
+		go world.run(stop)
+	}
+	errs := make(chan error, 2)
+	if *sshAddr != "" {
+		signer, err := loadHostKey(*hostKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't load host key: %v\n", err)
+			return 1
+		}
+		l, err := net.Listen("tcp", *sshAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't serve: %v\n", err)
+			return 1
+		}
+		defer l.Close()
+		host, port, _ := net.SplitHostPort(joinAddress(l))
+		fmt.Fprintf(os.Stderr, "Serving on %s: ssh -p %s %s\n", l.Addr(), port, host)
+		sessionWorld := world
+		if !*shared {
+			sessionWorld = nil // A scene each
+		}
+		server := newSSHServer(scene, signer, sessionWorld)
+		go func() { errs <- server.serve(newLimitListener(l, *maxConns)) }()
+	}
+	if *httpAddr != "" {
+		l, err := net.Listen("tcp", *httpAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't serve: %v\n", err)
+			return 1
+		}
+		defer l.Close()
+		fmt.Fprintf(os.Stderr, "Serving on %s: open http://%s/\n", l.Addr(), joinAddress(l))
+		server := newHTTPServer(newWebServer(scene, world))
+		go func() { errs <- server.Serve(newLimitListener(l, *maxConns)) }()
+	}
+
+	if err := <-errs; err != nil {
+		fmt.Fprintf(os.Stderr, "Alas, there's been an error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshTestServer serves the default scene on localhost and returns its address.
+func sshTestServer(t *testing.T, world *sharedWorld) string {
+	t.Helper()
+	signer, err := loadHostKey(filepath.Join(t.TempDir(), "host_key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go newSSHServer(defaultScene(), signer, world).serve(l)
+	return l.Addr().String()
+}
+
+// sshTestSession connects to addr and opens a session.
+func sshTestSession(t *testing.T, addr string) *ssh.Session {
+	t.Helper()
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "ana",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+// lockedBuffer is a bytes.Buffer that's safe to read while it's written to.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor waits for the session's output to contain want.
+func waitFor(t *testing.T, out *lockedBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("output never contained %q; got %q", want, out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeSSHSession(t *testing.T) {
+	session := sshTestSession(t, sshTestServer(t, nil))
+	var out lockedBuffer
+	session.Stdout = &out
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.RequestPty("xterm-256color", 5, 15, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Too small for the scene, which shows the session got the client's size
+	waitFor(t, &out, "15x5, need 20x5")
+	if err := session.WindowChange(12, 40); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, &out, "\x1b[38;5;") // The scene, in 256 colours
+
+	io.WriteString(stdin, "q")
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("session ended with %v, want a clean exit", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("session didn't end after q")
+	}
+}
+
+func TestServeLimitsSessions(t *testing.T) {
+	addr := sshTestServer(t, nil)
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "ana",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var sessions []*ssh.Session
+	for i := 0; i < maxSSHSessions; i++ {
+		session, err := client.NewSession()
+		if err != nil {
+			t.Fatalf("session %d: %v", i+1, err)
+		}
+		sessions = append(sessions, session)
+	}
+	if _, err := client.NewSession(); err == nil {
+		t.Fatalf("opened %d sessions on one connection, want at most %d", maxSSHSessions+1, maxSSHSessions)
+	}
+	sessions[0].Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		session, err := client.NewSession()
+		if err == nil {
+			session.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no new session after closing one: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLimitListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newLimitListener(inner, 1)
+	defer l.Close()
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+	dial := func() net.Conn {
+		c, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+		return c
+	}
+
+	dial()
+	first := <-accepted
+	extra := dial()
+	if _, err := extra.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read from a connection over the limit: %v, want EOF as it's hung up on", err)
+	}
+	first.Close()
+	dial()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(5 * time.Second):
+		t.Error("no connection accepted after the first closed")
+	}
+}
+
+func TestServeNeedsTerminal(t *testing.T) {
+	session := sshTestSession(t, sshTestServer(t, nil))
+	var stderr lockedBuffer
+	session.Stderr = &stderr
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	var exit *ssh.ExitError
+	if err := session.Wait(); !errors.As(err, &exit) || exit.ExitStatus() != 1 {
+		t.Errorf("session without a terminal ended with %v, want exit status 1", err)
+	}
+	if !strings.Contains(stderr.String(), "needs a terminal") {
+		t.Errorf("stderr = %q, want it to say a terminal is needed", stderr.String())
+	}
+}
+
+func TestServeRejectsHugeTerminal(t *testing.T) {
+	session := sshTestSession(t, sshTestServer(t, nil))
+	var stderr lockedBuffer
+	session.Stderr = &stderr
+	if err := session.RequestPty("xterm", 65535, 65535, nil); err == nil {
+		t.Fatal("pty-req for a 65535x65535 terminal accepted")
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	session.Wait()
+	if !strings.Contains(stderr.String(), "needs a terminal") {
+		t.Errorf("stderr = %q, want the session refused as it has no usable terminal", stderr.String())
+	}
+}
+
+func TestLoadHostKeyKeepsKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "host_key")
+	first, err := loadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("host key saved with %v, %v; want mode 0600", info.Mode(), err)
+	}
+	second, err := loadHostKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.PublicKey().Marshal(), second.PublicKey().Marshal()) {
+		t.Error("host key changed between runs")
+	}
+}
+
+func TestWatchWorldScales(t *testing.T) {
+	r := *newTestRunner(40, 10, 1, [][]string{{"AA", "AA"}})
+	world := &sharedWorld{m: sceneModel(r)}
+	world.m.termWidth, world.m.termHeight = 80, 12
+	world.m.accumulator = world.m.tickRate / 2
+
+	m := seededModel(defaultScene(), display{}, 1)
+	m.world = world
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 160, Height: 22})
+	m = updated.(model)
+	updated, _ = m.Update(tickMsg(time.Now()))
+	m = updated.(model)
+
+	if len(m.runners) != 1 {
+		t.Fatalf("watching a world of 1 runner, got %d", len(m.runners))
+	}
+	// Twice as wide, and twice the room to move up and down in
+	if got := m.runners[0].Pos; got != (Position{X: 80, Y: 20}) {
+		t.Errorf("runner at %+v, want (80, 20)", got)
+	}
+	if m.interpolationAlpha() != 0.5 {
+		t.Errorf("interpolation at %v, want the world's 0.5", m.interpolationAlpha())
+	}
+	if world.m.runners[0].Pos != (Position{X: 40, Y: 10}) {
+		t.Errorf("watching moved the world's runner to %+v", world.m.runners[0].Pos)
+	}
+}
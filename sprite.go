@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- Sprite files ---
+//
+// Runner art can be loaded from plain-text sprite files instead of the
+// built-in placeholders in art.go. A sprite file has an optional header of
+// "key = value" lines (and # comments), followed by one or more frames, each
+// introduced by a "--- frame" line. Frame lines are used verbatim, so leading
+// and trailing spaces are significant:
+//
+//	# Jogger, drawn by hand
+//	type = jogger
+//	color.h = 196
+//	color.j = jersey
+//	--- frame
+//	   ____
+//	  / oo \
+//	--- mask
+//	   hhhh
+//	  jjjjjj
+//	--- frame
+//	   ____
+//	  \ oo /
+//
+// A frame may be followed by a "--- mask" section of the same shape, holding
+// one palette key per glyph. Each "color.<key>" header line gives a key its
+// colour (an ANSI index, #rrggbb, or "jersey" for the runner's own colour);
+// a space in the mask leaves the glyph in the terminal's default colour.
+// Frames without a mask are drawn entirely in the runner's colour.
+//
+// Spaces in the art are transparent: they aren't drawn, so the scenery and
+// other runners show through. To draw spaces (e.g. to hide what's behind a
+// face), set "transparent = <glyph>" in the header and use that glyph for
+// the see-through cells instead.
+//
+// The runner type defaults to the file name, so "trail-runner.sprite" needs no
+// header at all.
+//
+// A runner type can have art in up to three sizes, and the largest that fits
+// the terminal is drawn (see resize.go). "size = medium" or "size = tiny" in
+// the header (or a file name like "jogger.tiny.sprite") makes a file one of
+// the smaller sizes; without either it's the large art.
+
+const (
+	spriteExt         = ".sprite"
+	spriteFrameMarker = "--- frame"
+	spriteMaskMarker  = "--- mask"
+	spriteColorPrefix = "color."
+
+	// jerseyColor in a palette stands for the runner's own (random or
+	// configured) colour.
+	jerseyColor = "jersey"
+)
+
+// spriteSize is which of a runner type's art sizes a sprite is. Larger sizes
+// sort first; the zero value is the large art.
+type spriteSize int
+
+const (
+	sizeLarge spriteSize = iota
+	sizeMedium
+	sizeTiny
+)
+
+// spriteSizes lists the sizes from largest to smallest.
+var spriteSizes = []spriteSize{sizeLarge, sizeMedium, sizeTiny}
+
+func (sz spriteSize) String() string {
+	switch sz {
+	case sizeMedium:
+		return "medium"
+	case sizeTiny:
+		return "tiny"
+	}
+	return "large"
+}
+
+// parseSpriteSize converts a size name to a spriteSize.
+func parseSpriteSize(name string) (spriteSize, error) {
+	for _, sz := range spriteSizes {
+		if name == sz.String() {
+			return sz, nil
+		}
+	}
+	return sizeLarge, fmt.Errorf("unknown sprite size %q (want large, medium or tiny)", name)
+}
+
+// spriteKey identifies a loaded sprite: one per runner type and size.
+type spriteKey struct {
+	Type RunnerType
+	Size spriteSize
+}
+
+// Sprite is a parsed sprite file.
+type Sprite struct {
+	Type   RunnerType
+	Size   spriteSize
+	Frames [][]string // Each inner slice is a frame, each string is a line of the frame
+
+	// Masks[i] is the colour mask for frame i, or nil if it has none. Each
+	// mask line has one palette key per rune of the matching frame line.
+	Masks   [][]string
+	Palette map[rune]string // Mask key -> colour string or jerseyColor
+
+	Transparent rune // Glyph that isn't drawn, letting the scene show through; 0 means the default, a space (see transparentGlyph)
+
+	// FrameLines[i] is the 1-based file line of the first row of frame i, so
+	// diagnostics can point back into the file.
+	FrameLines []int
+}
+
+// parseSprite parses sprite file contents. name is used for error messages and,
+// when the header has no type, to derive the runner type from the file name.
+func parseSprite(name string, data []byte) (*Sprite, error) {
+	s := &Sprite{Palette: map[rune]string{}}
+	typeSet, sizeSet := false, false
+	inHeader := true
+	inMask := false
+	var maskLines []int // Line of each frame's "--- mask" marker, 0 if none
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		switch line {
+		case spriteFrameMarker:
+			inHeader, inMask = false, false
+			s.Frames = append(s.Frames, []string{})
+			s.Masks = append(s.Masks, nil)
+			s.FrameLines = append(s.FrameLines, lineNo+1)
+			maskLines = append(maskLines, 0)
+			continue
+		case spriteMaskMarker:
+			last := len(s.Frames) - 1
+			if last < 0 || s.Masks[last] != nil || inMask {
+				return nil, fmt.Errorf("%s:%d: %q must follow a frame, once per frame", name, lineNo, spriteMaskMarker)
+			}
+			inMask = true
+			s.Masks[last] = []string{}
+			maskLines[last] = lineNo
+			continue
+		}
+		if !inHeader {
+			last := len(s.Frames) - 1
+			if inMask {
+				s.Masks[last] = append(s.Masks[last], line)
+			} else {
+				s.Frames[last] = append(s.Frames[last], line)
+			}
+			continue
+		}
+
+		// Header section
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		eq := strings.Index(trimmed, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: expected \"key = value\" or %q, got %q", name, lineNo, spriteFrameMarker, trimmed)
+		}
+		key := strings.TrimSpace(trimmed[:eq])
+		value := strings.Trim(strings.TrimSpace(trimmed[eq+1:]), `"`)
+		switch {
+		case key == "type":
+			rt, err := parseRunnerType(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, lineNo, err)
+			}
+			s.Type = rt
+			typeSet = true
+		case key == "size":
+			sz, err := parseSpriteSize(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, lineNo, err)
+			}
+			s.Size = sz
+			sizeSet = true
+		case key == "transparent":
+			glyph := []rune(value)
+			if len(glyph) != 1 {
+				return nil, fmt.Errorf("%s:%d: transparent must be a single glyph, got %q", name, lineNo, value)
+			}
+			s.Transparent = glyph[0]
+		case strings.HasPrefix(key, spriteColorPrefix):
+			paletteKey := []rune(strings.TrimPrefix(key, spriteColorPrefix))
+			if len(paletteKey) != 1 || paletteKey[0] == ' ' {
+				return nil, fmt.Errorf("%s:%d: palette key in %q must be a single non-space character", name, lineNo, key)
+			}
+			if value != jerseyColor {
+				var c colorValue
+				if err := c.UnmarshalText([]byte(value)); err != nil {
+					return nil, fmt.Errorf("%s:%d: %v", name, lineNo, err)
+				}
+			}
+			s.Palette[paletteKey[0]] = value
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown header key %q", name, lineNo, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if !typeSet || !sizeSet {
+		rt, sz, err := parseSpriteFileName(name)
+		if !typeSet {
+			if err != nil {
+				return nil, fmt.Errorf("%s: no type header and file name doesn't name a runner type: %v", name, err)
+			}
+			s.Type = rt
+		}
+		if !sizeSet && err == nil {
+			s.Size = sz
+		}
+	}
+	if len(s.Frames) == 0 {
+		return nil, fmt.Errorf("%s: no frames (start each frame with a %q line)", name, spriteFrameMarker)
+	}
+	hasMask := false
+	for i, frame := range s.Frames {
+		if len(frame) == 0 {
+			return nil, fmt.Errorf("%s:%d: frame %d is empty", name, s.FrameLines[i]-1, i+1)
+		}
+		if s.Masks[i] == nil {
+			continue
+		}
+		hasMask = true
+		if err := checkMask(s, i); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, maskLines[i]+err.line, err.msg)
+		}
+	}
+	if !hasMask {
+		s.Masks = nil
+	}
+	return s, nil
+}
+
+// transparentGlyph returns the glyph that isn't drawn for this sprite.
+func (s *Sprite) transparentGlyph() rune {
+	if s.Transparent == 0 {
+		return ' '
+	}
+	return s.Transparent
+}
+
+// maskError locates a mask problem relative to its "--- mask" line.
+type maskError struct {
+	line int // 0 for the marker itself, 1 for the first mask line, ...
+	msg  string
+}
+
+// checkMask verifies that frame i's mask matches the frame's shape and only
+// uses keys from the palette.
+func checkMask(s *Sprite, i int) *maskError {
+	frame, mask := s.Frames[i], s.Masks[i]
+	if len(mask) != len(frame) {
+		return &maskError{0, fmt.Sprintf("mask for frame %d has %d lines, frame has %d", i+1, len(mask), len(frame))}
+	}
+	for l, maskLine := range mask {
+		keys := []rune(maskLine)
+		if want := len([]rune(frame[l])); len(keys) != want {
+			return &maskError{l + 1, fmt.Sprintf("mask line has %d keys, frame line has %d glyphs", len(keys), want)}
+		}
+		for _, k := range keys {
+			if _, ok := s.Palette[k]; k != ' ' && !ok {
+				return &maskError{l + 1, fmt.Sprintf("mask key %q has no color.%c line in the header", k, k)}
+			}
+		}
+	}
+	return nil
+}
+
+// loadSprite reads and parses a single sprite file.
+func loadSprite(path string) (*Sprite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseSprite(path, data)
+}
+
+// formatSprite serializes a sprite in the format parseSprite reads.
+func formatSprite(s *Sprite) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "type = %s\n", spriteName(s.Type))
+	if s.Size != sizeLarge {
+		fmt.Fprintf(&b, "size = %s\n", s.Size)
+	}
+	if g := s.transparentGlyph(); g != ' ' {
+		fmt.Fprintf(&b, "transparent = %c\n", g)
+	}
+	keys := make([]rune, 0, len(s.Palette))
+	for k := range s.Palette {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s%c = %s\n", spriteColorPrefix, k, s.Palette[k])
+	}
+	for i, frame := range s.Frames {
+		b.WriteString(spriteFrameMarker + "\n")
+		for _, line := range frame {
+			b.WriteString(line + "\n")
+		}
+		if i < len(s.Masks) && s.Masks[i] != nil {
+			b.WriteString(spriteMaskMarker + "\n")
+			for _, line := range s.Masks[i] {
+				b.WriteString(line + "\n")
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+// saveSprite writes a sprite file, creating its directory if needed.
+func saveSprite(path string, s *Sprite) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, formatSprite(s), 0o644)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers (like the hot-reload watcher) never see a half-written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// spriteName is the canonical lower-case name of a runner type, used in sprite
+// headers and default file names.
+func spriteName(rt RunnerType) string {
+	return strings.ToLower(rt.String())
+}
+
+// parseSpriteFileName gets the runner type and size from a sprite file name
+// like "jogger.sprite" or "jogger.medium.sprite".
+func parseSpriteFileName(path string) (RunnerType, spriteSize, error) {
+	base := strings.TrimSuffix(filepath.Base(path), spriteExt)
+	size := sizeLarge
+	if dot := strings.LastIndex(base, "."); dot >= 0 {
+		sz, err := parseSpriteSize(base[dot+1:])
+		if err != nil {
+			return 0, sizeLarge, err
+		}
+		base, size = base[:dot], sz
+	}
+	rt, err := parseRunnerType(base)
+	return rt, size, err
+}
+
+// spriteFiles lists the sprite files in dir in a stable order. A missing
+// directory simply has no sprites.
+func spriteFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spriteExt) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadSpriteDir loads every sprite file in dir, keyed by runner type and size.
+func loadSpriteDir(dir string) (map[spriteKey]*Sprite, error) {
+	files, err := spriteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	sprites := make(map[spriteKey]*Sprite, len(files))
+	for _, path := range files {
+		s, err := loadSprite(path)
+		if err != nil {
+			return nil, err
+		}
+		sprites[spriteKey{s.Type, s.Size}] = s
+	}
+	return sprites, nil
+}
+
+// --- Loaded art registry ---
+
+var (
+	loadedArtMu sync.RWMutex
+	loadedArt   map[spriteKey]*Sprite // Sprites from disk; these take precedence over the built-in art
+)
+
+// setLoadedArt replaces the set of sprites loaded from disk.
+func setLoadedArt(sprites map[spriteKey]*Sprite) {
+	loadedArtMu.Lock()
+	defer loadedArtMu.Unlock()
+	loadedArt = sprites
+}
+
+// lookupLoadedArt returns the sprite loaded from disk for a runner type and
+// size, if any.
+func lookupLoadedArt(rt RunnerType, size spriteSize) (*Sprite, bool) {
+	loadedArtMu.RLock()
+	defer loadedArtMu.RUnlock()
+	s, ok := loadedArt[spriteKey{rt, size}]
+	return s, ok
+}
+
+// defaultSpriteDir returns the per-user sprite directory, next to the config file.
+func defaultSpriteDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "consolerunner", "sprites")
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSprite = `# A tiny two-frame jogger
+type = marathoner
+--- frame
+ o 
+/|\
+--- frame
+ o 
+ |\
+`
+
+func TestParseSprite(t *testing.T) {
+	s, err := parseSprite("tiny.sprite", []byte(testSprite))
+	if err != nil {
+		t.Fatalf("parseSprite() error = %v", err)
+	}
+	if s.Type != Marathoner {
+		t.Errorf("Type = %v, want Marathoner", s.Type)
+	}
+	want := [][]string{{" o ", "/|\\"}, {" o ", " |\\"}}
+	if !reflect.DeepEqual(s.Frames, want) {
+		t.Errorf("Frames = %q, want %q", s.Frames, want)
+	}
+	if !reflect.DeepEqual(s.FrameLines, []int{4, 7}) {
+		t.Errorf("FrameLines = %v, want [4 7]", s.FrameLines)
+	}
+}
+
+func TestParseSpriteTypeFromFileName(t *testing.T) {
+	s, err := parseSprite("/art/trail-runner.sprite", []byte("--- frame\n>\n"))
+	if err != nil {
+		t.Fatalf("parseSprite() error = %v", err)
+	}
+	if s.Type != TrailRunner {
+		t.Errorf("Type = %v, want TrailRunner", s.Type)
+	}
+}
+
+func TestParseSpriteErrors(t *testing.T) {
+	tests := []struct {
+		name, file, contents, want string
+	}{
+		{"no frames", "jogger.sprite", "type = jogger\n", "no frames"},
+		{"bad type", "x.sprite", "\ntype = sprinter\n--- frame\nx\n", `x.sprite:2: unknown runner type "sprinter"`},
+		{"unknown header", "jogger.sprite", "colour = red\n", `jogger.sprite:1: unknown header key "colour"`},
+		{"empty frame", "jogger.sprite", "--- frame\n--- frame\nx\n", "jogger.sprite:1: frame 1 is empty"},
+		{"type from bad name", "hat.sprite", "--- frame\nx\n", "no type header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSprite(tt.file, []byte(tt.contents))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseSprite() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpriteTransparentGlyph(t *testing.T) {
+	s, err := parseSprite("jogger.sprite", []byte("transparent = .\n--- frame\n.o.\n"))
+	if err != nil {
+		t.Fatalf("parseSprite() error = %v", err)
+	}
+	if s.transparentGlyph() != '.' {
+		t.Errorf("transparentGlyph() = %q, want '.'", s.transparentGlyph())
+	}
+	round, err := parseSprite("jogger.sprite", formatSprite(s))
+	if err != nil || round.Transparent != '.' {
+		t.Errorf("round trip Transparent = %q (err %v), want '.'", round.Transparent, err)
+	}
+
+	plain, _ := parseSprite("jogger.sprite", []byte("--- frame\no\n"))
+	if plain.transparentGlyph() != ' ' || strings.Contains(string(formatSprite(plain)), "transparent") {
+		t.Errorf("default sprite transparentGlyph() = %q, want a space and no header", plain.transparentGlyph())
+	}
+
+	if _, err := parseSprite("jogger.sprite", []byte("transparent = ..\n--- frame\no\n")); err == nil || !strings.Contains(err.Error(), "jogger.sprite:1: transparent must be a single glyph") {
+		t.Errorf("parseSprite() error = %v, want a single-glyph error", err)
+	}
+}
+
+func TestLoadedArtOverridesBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "jogger.sprite"), []byte("--- frame\nJ\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	art, err := loadSpriteDir(dir)
+	if err != nil {
+		t.Fatalf("loadSpriteDir() error = %v", err)
+	}
+	setLoadedArt(art)
+	defer setLoadedArt(nil)
+
+	if got := getArtForType(Jogger); !reflect.DeepEqual(got, [][]string{{"J"}}) {
+		t.Errorf("getArtForType(Jogger) = %q, want the loaded sprite", got)
+	}
+	if got := getArtForType(TenKRunner); !reflect.DeepEqual(got, tenKRunnerArt) {
+		t.Error("getArtForType(TenKRunner) should fall back to the built-in art")
+	}
+}
+
+func TestReloadKeepsRunnerPosition(t *testing.T) {
+	defer setLoadedArt(nil)
+	m := newTestModel(time.Second / defaultFPS)
+	m.runners[0].Type = Jogger
+	m.runners[0].ArtFrames = joggerArt
+	m.runners[0].CurrentFrameIdx = 3
+	m.runners[0].Pos = Position{X: 12.5, Y: 4}
+
+	reload := reloadMsg{scene: defaultScene(), art: map[spriteKey]*Sprite{{Jogger, sizeLarge}: {Type: Jogger, Frames: [][]string{{"a"}, {"b"}}}}}
+	updated, _ := m.Update(reload)
+	r := updated.(model).runners[0]
+	if r.Pos != (Position{X: 12.5, Y: 4}) {
+		t.Errorf("reload moved runner to %+v", r.Pos)
+	}
+	if !reflect.DeepEqual(r.ArtFrames, [][]string{{"a"}, {"b"}}) || r.CurrentFrameIdx != 1 {
+		t.Errorf("reload left art %q frame %d, want new art at frame 1", r.ArtFrames, r.CurrentFrameIdx)
+	}
+
+	failed, _ := updated.(model).Update(reloadMsg{err: os.ErrPermission})
+	if status := failed.(model).status; !strings.Contains(status, "reload failed") {
+		t.Errorf("status after failed reload = %q, want an error message", status)
+	}
+	if got := failed.(model).runners[0].ArtFrames; !reflect.DeepEqual(got, [][]string{{"a"}, {"b"}}) {
+		t.Error("failed reload should keep the previous art")
+	}
+}
+
+func TestWatcherDetectsSpriteChanges(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(config, []byte("sprite_dir = \".\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w := newWatcher(config, "", nil, dir)
+	if changed(w.last, w.snapshot(dir)) {
+		t.Fatal("snapshot changed without any file changes")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "jogger.sprite"), []byte("--- frame\nJ\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !changed(w.last, w.snapshot(dir)) {
+		t.Fatal("new sprite file not detected")
+	}
+	msg := w.reload()
+	if msg.err != nil {
+		t.Fatalf("reload() error = %v", msg.err)
+	}
+	if msg.scene.SpriteDir != dir {
+		t.Errorf("reloaded sprite_dir = %q, want %q (relative to the config)", msg.scene.SpriteDir, dir)
+	}
+	if _, ok := msg.art[spriteKey{Jogger, sizeLarge}]; !ok {
+		t.Error("reload() didn't load the new sprite")
+	}
+}
+
+const testMaskedSprite = `type = jogger
+color.h = 196
+color.j = jersey
+--- frame
+ o 
+/|\
+--- mask
+ h 
+jjj
+--- frame
+ o 
+ |\
+`
+
+func TestParseSpriteMasks(t *testing.T) {
+	s, err := parseSprite("jogger.sprite", []byte(testMaskedSprite))
+	if err != nil {
+		t.Fatalf("parseSprite() error = %v", err)
+	}
+	if want := [][]string{{" h ", "jjj"}, nil}; !reflect.DeepEqual(s.Masks, want) {
+		t.Errorf("Masks = %q, want %q", s.Masks, want)
+	}
+	if want := map[rune]string{'h': "196", 'j': jerseyColor}; !reflect.DeepEqual(s.Palette, want) {
+		t.Errorf("Palette = %v, want %v", s.Palette, want)
+	}
+
+	// Formatting and parsing again must give the same sprite
+	again, err := parseSprite("jogger.sprite", formatSprite(s))
+	if err != nil {
+		t.Fatalf("re-parse error = %v", err)
+	}
+	if !reflect.DeepEqual(again.Frames, s.Frames) || !reflect.DeepEqual(again.Masks, s.Masks) || !reflect.DeepEqual(again.Palette, s.Palette) {
+		t.Errorf("round trip changed sprite:\n%s", formatSprite(again))
+	}
+}
+
+func TestParseSpriteMaskErrors(t *testing.T) {
+	tests := []struct {
+		name, contents, want string
+	}{
+		{"mask before frame", "--- mask\nx\n", `jogger.sprite:1: "--- mask" must follow a frame`},
+		{"two masks", "color.a = 1\n--- frame\nx\n--- mask\na\n--- mask\na\n", `jogger.sprite:6: "--- mask" must follow a frame`},
+		{"short mask", "color.a = 1\n--- frame\nxy\nz\n--- mask\naa\n", "jogger.sprite:5: mask for frame 1 has 1 lines, frame has 2"},
+		{"narrow mask line", "color.a = 1\n--- frame\nxy\n--- mask\na\n", "jogger.sprite:5: mask line has 1 keys, frame line has 2 glyphs"},
+		{"unknown key", "color.a = 1\n--- frame\nxy\n--- mask\nab\n", `jogger.sprite:5: mask key 'b' has no color.b line`},
+		{"bad colour", "color.a = teal\n--- frame\nx\n", `jogger.sprite:1: invalid color "teal"`},
+		{"long key", "color.ab = 1\n--- frame\nx\n", "jogger.sprite:1: palette key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSprite("jogger.sprite", []byte(tt.contents))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseSprite() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinMasksFitArt(t *testing.T) {
+	for rt := Jogger; rt <= TenKRunner; rt++ {
+		if s := getSpriteForType(rt); s.Masks == nil {
+			t.Errorf("built-in %v art has no usable colour mask", rt)
+		}
+	}
+}
+
+func TestParseSpriteSize(t *testing.T) {
+	s, err := parseSprite("jogger.tiny.sprite", []byte("--- frame\no>\n"))
+	if err != nil {
+		t.Fatalf("parseSprite() error = %v", err)
+	}
+	if s.Type != Jogger || s.Size != sizeTiny {
+		t.Errorf("from file name: type %v size %v, want jogger tiny", s.Type, s.Size)
+	}
+
+	s, err = parseSprite("anything.sprite", []byte("type = marathoner\nsize = medium\n--- frame\n o \n"))
+	if err != nil {
+		t.Fatalf("parseSprite() error = %v", err)
+	}
+	if s.Type != Marathoner || s.Size != sizeMedium {
+		t.Errorf("from header: type %v size %v, want marathoner medium", s.Type, s.Size)
+	}
+	if again, err := parseSprite("x.sprite", formatSprite(s)); err != nil || again.Size != sizeMedium {
+		t.Errorf("formatSprite() round trip lost the size: %v, %v", again, err)
+	}
+
+	if _, err := parseSprite("jogger.sprite", []byte("size = huge\n--- frame\nx\n")); err == nil || !strings.Contains(err.Error(), "jogger.sprite:1:") {
+		t.Errorf("unknown size error = %v, want one pointing at line 1", err)
+	}
+}
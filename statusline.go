@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Status line (consolerunner statusline) ---
+//
+// Prints a single line with tiny runners moving along a track, for tmux's
+// status-right or a shell prompt. There's no long-running process: a state
+// file records when the race started and each runner's speed, and every call
+// works out where the runners are now from the clock. Called once a second,
+// the runners appear to move continuously.
+//
+//	set -g status-interval 1
+//	set -g status-right "#(consolerunner statusline --format tmux)"
+
+const (
+	defaultStatuslineWidth   = 20
+	defaultStatuslineRunners = 3
+)
+
+// statuslineFormat is how colours are written: not at all, as ANSI escape
+// sequences (for prompts) or as tmux #[fg=...] markup.
+type statuslineFormat string
+
+const (
+	statuslinePlain statuslineFormat = "plain"
+	statuslineANSI  statuslineFormat = "ansi"
+	statuslineTmux  statuslineFormat = "tmux"
+)
+
+// Two-frame single-cell runners, and the track they run on.
+var (
+	statuslineFrames      = [2]rune{'λ', 'ʎ'}
+	statuslineASCIIFrames = [2]rune{'>', '}'}
+)
+
+const (
+	statuslineTrack      = '·'
+	statuslineASCIITrack = '.'
+)
+
+// statuslineState is the state file: everything needed to place the runners
+// at any moment.
+type statuslineState struct {
+	Start   time.Time          `json:"start"`
+	Width   int                `json:"width"`
+	Runners []statuslineRunner `json:"runners"`
+}
+
+// statuslineRunner is one runner on the track.
+type statuslineRunner struct {
+	Speed  float64 `json:"speed"`  // Cells per second
+	Offset float64 `json:"offset"` // Position at Start
+	Color  string  `json:"color"`  // ANSI index
+}
+
+// newStatuslineState starts a new race.
+func newStatuslineState(now time.Time, width, runners int, rng *rand.Rand) statuslineState {
+	s := statuslineState{Start: now, Width: width}
+	theme := colorThemes[colorThemeClassic]
+	for i := 0; i < runners; i++ {
+		s.Runners = append(s.Runners, statuslineRunner{
+			Speed:  rng.Float64()*1.5 + 0.5, // Same range as the full-screen runners, per second
+			Offset: rng.Float64() * float64(width),
+			Color:  theme.pickJersey(rng, true),
+		})
+	}
+	return s
+}
+
+// loadStatuslineState reads the state file, starting a new race if there
+// isn't one or it was for a different track.
+func loadStatuslineState(path string, now time.Time, width, runners int) (statuslineState, error) {
+	var s statuslineState
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &s) == nil &&
+		s.Width == width && len(s.Runners) == runners && !s.Start.After(now) {
+		return s, nil
+	}
+
+	s = newStatuslineState(now, width, runners, rand.New(rand.NewSource(now.UnixNano())))
+	data, err := json.Marshal(s)
+	if err != nil {
+		return s, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return s, err
+	}
+	return s, writeFileAtomic(path, data, 0o644)
+}
+
+// renderStatusline draws the track at now, exactly s.Width cells wide.
+func renderStatusline(s statuslineState, now time.Time, format statuslineFormat, ascii bool) string {
+	frames, track := statuslineFrames, statuslineTrack
+	if ascii {
+		frames, track = statuslineASCIIFrames, statuslineASCIITrack
+	}
+
+	cells := make([]rune, s.Width)
+	colors := make([]string, s.Width)
+	for i := range cells {
+		cells[i] = track
+	}
+	elapsed := now.Sub(s.Start).Seconds()
+	for i, r := range s.Runners {
+		x := int(math.Mod(r.Offset+r.Speed*elapsed, float64(s.Width)))
+		cells[x] = frames[(int(elapsed)+i)%2] // Stride once a second, out of step
+		colors[x] = r.Color
+	}
+
+	var b strings.Builder
+	for i, c := range cells {
+		switch {
+		case colors[i] == "" || format == statuslinePlain:
+			b.WriteRune(c)
+		case format == statuslineTmux:
+			fmt.Fprintf(&b, "#[fg=colour%s]%c#[default]", colors[i], c)
+		default:
+			fmt.Fprintf(&b, "\x1b[38;5;%sm%c\x1b[0m", colors[i], c)
+		}
+	}
+	return b.String()
+}
+
+// defaultStatuslineStatePath returns the per-user state file location.
+func defaultStatuslineStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "consolerunner", "statusline.json")
+}
+
+// runStatusline implements "consolerunner statusline".
+func runStatusline(args []string) int {
+	fs := flag.NewFlagSet("statusline", flag.ContinueOnError)
+	width := fs.Int("width", defaultStatuslineWidth, "track width in cells")
+	runners := fs.Int("runners", defaultStatuslineRunners, "number of runners")
+	format := fs.String("format", string(statuslinePlain), "colours: plain, ansi (for prompts) or tmux")
+	statePath := fs.String("state", defaultStatuslineStatePath(), "state file remembering the race between calls")
+	charsetFlag := fs.String("charset", string(charsetAuto), "glyphs to use: auto, unicode or ascii")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: consolerunner statusline [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	if *width <= 0 || *runners < 0 {
+		fmt.Fprintln(os.Stderr, "--width must be greater than zero and --runners must not be negative")
+		return 2
+	}
+	switch statuslineFormat(*format) {
+	case statuslinePlain, statuslineANSI, statuslineTmux:
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid --format %q (want plain, ansi or tmux)\n", *format)
+		return 2
+	}
+	charset, err := parseCharsetMode(*charsetFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --charset: %v\n", err)
+		return 2
+	}
+
+	now := time.Now()
+	s, err := loadStatuslineState(*statePath, now, *width, *runners)
+	if err != nil {
+		// Still worth drawing; the next call starts another race
+		fmt.Fprintf(os.Stderr, "Can't save statusline state: %v\n", err)
+	}
+	fmt.Println(renderStatusline(s, now, statuslineFormat(*format), chooseASCII(charset, os.Getenv)))
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderStatusline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := statuslineState{Start: start, Width: 10, Runners: []statuslineRunner{{Speed: 2, Offset: 1, Color: "196"}}}
+
+	if got := renderStatusline(s, start, statuslinePlain, false); got != "·λ········" {
+		t.Errorf("at start = %q, want the runner at cell 1", got)
+	}
+	if got := renderStatusline(s, start.Add(time.Second), statuslinePlain, false); got != "···ʎ······" {
+		t.Errorf("after 1s = %q, want the runner 2 cells on, mid-stride", got)
+	}
+	if got := renderStatusline(s, start.Add(5*time.Second), statuslinePlain, true); got != ".}........" {
+		t.Errorf("after 5s (ascii) = %q, want the runner wrapped round to cell 1", got)
+	}
+	if got := renderStatusline(s, start, statuslineTmux, false); !strings.HasPrefix(got, "·#[fg=colour196]λ#[default]·") {
+		t.Errorf("tmux format = %q, want #[fg=...] markup around the runner", got)
+	}
+	if got := renderStatusline(s, start, statuslineANSI, false); !strings.Contains(got, "\x1b[38;5;196mλ\x1b[0m") {
+		t.Errorf("ansi format = %q, want an SGR sequence around the runner", got)
+	}
+}
+
+func TestStatuslineStatePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "statusline.json")
+	now := time.Now()
+
+	first, err := loadStatuslineState(path, now, 20, 3)
+	if err != nil {
+		t.Fatalf("loadStatuslineState() error = %v", err)
+	}
+	again, err := loadStatuslineState(path, now.Add(time.Second), 20, 3)
+	if err != nil || !again.Start.Equal(first.Start) || again.Runners[0] != first.Runners[0] {
+		t.Errorf("second call started a new race (err %v), want the saved one", err)
+	}
+
+	// A different track starts over
+	wider, _ := loadStatuslineState(path, now.Add(time.Second), 30, 3)
+	if wider.Width != 30 || wider.Start.Equal(first.Start) {
+		t.Errorf("after changing --width, state = width %d start %v, want a new 30-wide race", wider.Width, wider.Start)
+	}
+}
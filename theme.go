@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// --- Colour themes ---
+//
+// A colour theme (color_theme in the config, --color-theme on the command
+// line) sets the colours of the scenery and the jerseys runners are given.
+// It is independent of the light/dark "theme" setting, which only says what
+// colour the terminal background is. Themes with a sky paint the background
+// themselves, as a vertical gradient on truecolor terminals.
+
+// minJerseyContrast is the lowest WCAG contrast ratio a jersey may have
+// against the background (3:1 is the usual minimum for graphics).
+const minJerseyContrast = 3.0
+
+// colorTheme is a named set of scene colours (lipgloss colour strings).
+type colorTheme struct {
+	Sky       []string // Background gradient, top to bottom; nil leaves the terminal's own
+	Sun       string
+	Mountains [2]string // Top and bottom rows
+	Birds     string
+	Jerseys   []string // Runner colours to pick from; nil picks random 256-colour indexes
+}
+
+var colorThemes = map[colorThemeName]colorTheme{
+	colorThemeClassic: {
+		Sun:       "226", // Yellow
+		Mountains: [2]string{"240", "240"},
+		Birds:     "250",
+	},
+	colorThemeSolarized: {
+		Sky:       []string{"#002b36", "#073642"},
+		Sun:       "#b58900",
+		Mountains: [2]string{"#657b83", "#586e75"},
+		Birds:     "#93a1a1",
+		// Orange and magenta are lightened; the originals are too dark on base02
+		Jerseys: []string{"#e0734a", "#e76da8", "#268bd2", "#2aa198", "#859900", "#eee8d5"},
+	},
+	colorThemeNeon: {
+		Sky:       []string{"#0d0221", "#2e1065"},
+		Sun:       "#ff2a6d",
+		Mountains: [2]string{"#b537f2", "#7b2cbf"},
+		Birds:     "#05d9e8",
+		Jerseys:   []string{"#05d9e8", "#ff2a6d", "#d1f7ff", "#f9f871", "#39ff14", "#ff9e00"},
+	},
+	colorThemeMono: {
+		Sun:       "252",
+		Mountains: [2]string{"245", "241"},
+		Birds:     "248",
+		Jerseys:   []string{"255", "251", "247", "243", "239", "235", "232"},
+	},
+}
+
+// colors returns the model's colour theme.
+func (m model) colors() colorTheme {
+	if t, ok := colorThemes[m.colorTheme]; ok {
+		return t
+	}
+	return colorThemes[colorThemeClassic]
+}
+
+// backgrounds returns the colours jerseys are drawn on: the sky, or the
+// terminal's background (assumed black or white) if the theme has none.
+func (t colorTheme) backgrounds(dark bool) []rgb {
+	if len(t.Sky) == 0 {
+		if dark {
+			return []rgb{{0, 0, 0}}
+		}
+		return []rgb{{255, 255, 255}}
+	}
+	bgs := make([]rgb, 0, len(t.Sky))
+	for _, s := range t.Sky {
+		if c, err := parseColor(s); err == nil {
+			bgs = append(bgs, c)
+		}
+	}
+	return bgs
+}
+
+// readable reports whether color has enough contrast against every
+// background colour to be picked as a jersey.
+func (t colorTheme) readable(color string, dark bool) bool {
+	c, err := parseColor(color)
+	if err != nil {
+		return false
+	}
+	for _, bg := range t.backgrounds(dark) {
+		if contrastRatio(c, bg) < minJerseyContrast {
+			return false
+		}
+	}
+	return true
+}
+
+// pickJersey picks a random readable jersey colour for a dark or light
+// background. If nothing is readable it settles for any candidate.
+func (t colorTheme) pickJersey(rng *rand.Rand, dark bool) string {
+	if t.Jerseys == nil {
+		color := ""
+		for try := 0; try < 100; try++ {
+			color = strconv.Itoa(rng.Intn(230) + 16) // Skip the 16 system colours
+			if t.readable(color, dark) {
+				break
+			}
+		}
+		return color
+	}
+	var candidates []string
+	for _, c := range t.Jerseys {
+		if t.readable(c, dark) {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = t.Jerseys
+	}
+	return candidates[rng.Intn(len(candidates))]
+}
+
+// jersey picks a runner colour for both light and dark backgrounds.
+func (t colorTheme) jersey(rng *rand.Rand) lipgloss.AdaptiveColor {
+	return lipgloss.AdaptiveColor{Light: t.pickJersey(rng, false), Dark: t.pickJersey(rng, true)}
+}
+
+// skyColor returns the sky colour for row y of height rows, or "" if the
+// theme has no sky. Without truecolor the gradient would band badly in 256
+// colours, so the sky is a single colour.
+func (t colorTheme) skyColor(y, height int, truecolor bool) string {
+	if len(t.Sky) == 0 {
+		return ""
+	}
+	if !truecolor || len(t.Sky) == 1 || height < 2 {
+		return t.Sky[0]
+	}
+	// Position along the gradient, split evenly between its stops
+	pos := float64(y) / float64(height-1) * float64(len(t.Sky)-1)
+	i := int(pos)
+	if i >= len(t.Sky)-1 {
+		return t.Sky[len(t.Sky)-1]
+	}
+	from, err1 := parseColor(t.Sky[i])
+	to, err2 := parseColor(t.Sky[i+1])
+	if err1 != nil || err2 != nil {
+		return t.Sky[i]
+	}
+	return from.blend(to, pos-float64(i)).hex()
+}
+
+// paintSky fills the background of every cell that doesn't already have one
+// with the theme's sky.
+func (m model) paintSky(b *frameBuffer) {
+	t := m.colors()
+	if len(t.Sky) == 0 {
+		return
+	}
+	for y, row := range b.cells {
+		sky := t.skyColor(y, b.height, m.display.profile == termenv.TrueColor)
+		for x := range row {
+			if row[x].Background == "" {
+				row[x].Background = sky
+			}
+		}
+	}
+}
+
+// recolorRunners gives every runner without a configured colour a new jersey
+// from the current colour theme.
+func (m *model) recolorRunners() {
+	t := m.colors()
+	for i := range m.runners {
+		if !m.runners[i].FixedColor {
+			m.runners[i].Color = t.jersey(m.rng)
+		}
+	}
+}
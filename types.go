@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -29,13 +31,23 @@ type Position struct {
 // Runner represents a single animated runner on the screen.
 type Runner struct {
 	ID              int
+	Name            string // Optional display name from the config roster
 	Type            RunnerType
 	Pos             Position
-	VelocityX       float64    // Horizontal speed (cells per tick)
-	VelocityY       float64    // Vertical speed (cells per tick)
-	ArtFrames       [][]string // Each inner slice is a frame, each string is a line of the frame
+	PrevPos         Position        // Position at the previous simulation tick, used for render interpolation
+	VelocityX       float64         // Horizontal speed (cells per tick)
+	VelocityY       float64         // Vertical speed (cells per tick)
+	ArtFrames       [][]string      // Each inner slice is a frame, each string is a line of the frame
+	Masks           [][]string      // Optional colour mask per frame (nil for single-colour art)
+	Palette         map[rune]string // Mask key -> colour, or jerseyColor for Color
+	Transparent     rune            // Glyph that isn't drawn, letting the scene show through; the sprite's, a space unless it says otherwise (0 here draws every glyph)
+	Laps            int             // Times the runner has crossed the right edge
+	LapStart        int             // Tick the current lap started at, or -1 if it isn't being timed
+	Z               int             // Depth: higher is nearer; ties are broken by Y (lower on screen is nearer)
 	CurrentFrameIdx int
-	Color           lipgloss.AdaptiveColor // Use AdaptiveColor for better theme support
+	Size            spriteSize             // Which of the type's art sizes ArtFrames is
+	Color           lipgloss.AdaptiveColor // Jersey colour
+	FixedColor      bool                   // Color was configured, so colour themes leave it alone
 	rng             *rand.Rand             // Runner-specific RNG if needed, or use a global one
 }
 
@@ -63,3 +75,25 @@ func (rt RunnerType) String() string {
 		return "Unknown"
 	}
 }
+
+// parseRunnerType looks up a RunnerType by name. Matching ignores case, dashes
+// and underscores, so "trail-runner", "trail_runner" and "TrailRunner" all work.
+func parseRunnerType(name string) (RunnerType, error) {
+	normalized := strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(name))
+	for rt := Jogger; rt <= TenKRunner; rt++ {
+		if strings.ToLower(rt.String()) == normalized {
+			return rt, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown runner type %q", name)
+}
+
+// UnmarshalText lets RunnerType be decoded from config files by name.
+func (rt *RunnerType) UnmarshalText(text []byte) error {
+	parsed, err := parseRunnerType(string(text))
+	if err != nil {
+		return err
+	}
+	*rt = parsed
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Run "go test -run TestViewGolden -update" to rewrite the golden files after
+// an intended change to the output, and review the diff before committing.
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenModel builds a width x height model with a fixed seed and the
+// 256-colour profile, so its View() is the same on every machine.
+func goldenModel(scene Scene, width, height int) model {
+	m := newHeadlessModel(scene, termenv.ANSI256, 1, width, height)
+	m.runners = nil
+	return m
+}
+
+// goldenRunner is a runner with a fixed colour standing still at (x, y).
+func goldenRunner(x, y int, art ...string) Runner {
+	r := *newTestRunner(x, y, 0, [][]string{art})
+	r.PrevPos = r.Pos
+	r.Transparent = ' '
+	r.Color = lipgloss.AdaptiveColor{Light: "1", Dark: "208"}
+	return r
+}
+
+func TestViewGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		model func() model
+		ticks int
+	}{
+		{
+			// Sun, birds and mountains with nothing in front of them
+			name:  "scenery",
+			model: func() model { return goldenModel(defaultScene(), 40, 10) },
+		},
+		{
+			// The seeded crowd after it has been running a while
+			name: "crowd",
+			model: func() model {
+				return newHeadlessModel(defaultScene(), termenv.ANSI256, 7, 60, 16)
+			},
+			ticks: 30,
+		},
+		{
+			// Runners hanging off every edge are cut off, not wrapped or shifted
+			name: "clipping",
+			model: func() model {
+				m := goldenModel(defaultScene(), 20, 6)
+				m.background = nil
+				m.runners = []Runner{
+					goldenRunner(-2, 0, "LLLL", "LLLL"),
+					goldenRunner(17, 1, "RRRR", "RRRR"),
+					goldenRunner(4, -1, "TTTT", "TTTT"),
+					goldenRunner(5, 5, "BBBB", "BBBB"),
+				}
+				return m
+			},
+		},
+		{
+			// Wide glyphs take two cells, including where they overlap
+			// narrow glyphs and where an edge would cut them in half
+			name: "wide",
+			model: func() model {
+				m := goldenModel(defaultScene(), 20, 5)
+				m.background = []backgroundLayer{layerMountains}
+				m.runners = []Runner{
+					goldenRunner(1, 0, "日本語"),
+					goldenRunner(3, 0, "x"),
+					goldenRunner(-1, 1, "日本"),
+					goldenRunner(17, 1, "本日"),
+					goldenRunner(2, 2, "走る人"),
+				}
+				m.runners[1].Z = 1 // Over the middle of 日本語
+				return m
+			},
+		},
+		{
+			// Guidance instead of a scene that doesn't fit
+			name:  "too-small",
+			model: func() model { return goldenModel(defaultScene(), 16, 3) },
+		},
+		{
+			// Runners too tall for the terminal switch to the compact art
+			name: "compact",
+			model: func() model {
+				return newHeadlessModel(defaultScene(), termenv.ANSI256, 7, 40, 6)
+			},
+			ticks: 5,
+		},
+		{
+			// Five lanes in ten lines only leave room for the tiny art
+			name: "tiny",
+			model: func() model {
+				scene := defaultScene()
+				scene.Lanes = 5
+				return newHeadlessModel(scene, termenv.ANSI256, 7, 40, 10)
+			},
+			ticks: 5,
+		},
+		{
+			// The same scenery packed into half blocks
+			name: "halfblock",
+			model: func() model {
+				scene := defaultScene()
+				scene.Render = renderHalfBlock
+				return goldenModel(scene, 40, 10)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.model()
+			for i := 0; i < tt.ticks; i++ {
+				m.step()
+			}
+			got := m.View() + "\n"
+
+			path := filepath.Join("testdata", "view", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("View() doesn't match %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --- Hot reload ---
+//
+// While the animation runs we poll the config file and the sprite directory
+// for changes. When anything changes, both are reloaded and the result is sent
+// into the Bubble Tea program as a reloadMsg; the model swaps in the new art
+// and settings without resetting runner positions.
+
+const watchInterval = 500 * time.Millisecond
+
+// reloadMsg carries freshly loaded settings and sprites, or the error that
+// stopped them from loading.
+type reloadMsg struct {
+	scene Scene
+	art   map[spriteKey]*Sprite
+	err   error
+}
+
+// fileStamp is what we compare between polls to detect a change.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watcher polls a config file and sprite directory for modifications.
+type watcher struct {
+	configPath string // As given on the command line; empty means the default
+	profile    string
+	override   func(*Scene) // Reapplies command-line flags on top of each reload
+	interval   time.Duration
+	last       map[string]fileStamp
+}
+
+// newWatcher creates a watcher and takes an initial snapshot of spriteDir, the
+// sprite directory in use at startup, so only later changes trigger a reload.
+func newWatcher(configPath, profile string, override func(*Scene), spriteDir string) *watcher {
+	w := &watcher{configPath: configPath, profile: profile, override: override, interval: watchInterval}
+	w.last = w.snapshot(spriteDir)
+	return w
+}
+
+// snapshot stats the config file and every sprite file. Missing files are
+// simply absent from the snapshot, so creating or deleting one is a change.
+func (w *watcher) snapshot(spriteDir string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	paths := []string{}
+	if path, _ := resolveConfigPath(w.configPath); path != "" {
+		paths = append(paths, path)
+	}
+	if files, err := spriteFiles(spriteDir); err == nil {
+		paths = append(paths, files...)
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// changed reports whether two snapshots differ.
+func changed(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for path, stamp := range a {
+		if other, ok := b[path]; !ok || other != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// reload loads the config and the sprites it points at.
+func (w *watcher) reload() reloadMsg {
+	scene, err := loadScene(w.configPath, w.profile)
+	if err != nil {
+		return reloadMsg{err: err}
+	}
+	if w.override != nil {
+		w.override(&scene)
+	}
+	art, err := loadSpriteDir(scene.SpriteDir)
+	if err != nil {
+		return reloadMsg{scene: scene, err: err}
+	}
+	return reloadMsg{scene: scene, art: art}
+}
+
+// run polls until stop is closed, sending a reloadMsg whenever the watched
+// files change. The sprite directory itself may move if the config changes
+// sprite_dir, so it is re-read from each successful reload.
+func (w *watcher) run(p *tea.Program, spriteDir string, stop <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current := w.snapshot(spriteDir)
+			if !changed(w.last, current) {
+				continue
+			}
+			msg := w.reload()
+			if msg.err == nil {
+				if msg.scene.SpriteDir != spriteDir {
+					spriteDir = msg.scene.SpriteDir
+					current = w.snapshot(spriteDir)
+				}
+			}
+			w.last = current
+			p.Send(msg)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+// --- Watching in a browser (consolerunner serve --http) ---
+//
+// The server hands out a page with a small terminal emulator (web/term.js)
+// and streams the scene to it over a WebSocket, as the same escape
+// sequences a terminal would get. Every viewer watches the shared world
+// (see serve.go), drawn at the size of their browser window: the page sends
+// its size in cells as {"cols": N, "rows": N} when it connects and whenever
+// the window is resized, and the server sends back each frame View() draws
+// for that size. Viewers only watch; nothing they do reaches the world.
+//
+// Pages from other sites can't open the stream: a WebSocket handshake from
+// a browser has to come from a page with the same host (see websocket.go).
+//
+// The page and script are embedded in the binary, so it works offline.
+
+//go:embed web
+var webFiles embed.FS
+
+const (
+	webRequestTimeout = 10 * time.Second // To read or answer a request; not the stream, once it's started
+	webIdleTimeout    = time.Minute      // Between requests on a kept-alive connection
+)
+
+// webDisplay is what the page can show: any colour and any glyph, on the
+// page's dark background.
+var webDisplay = display{profile: termenv.TrueColor, dark: true}
+
+// webSize is a viewer's window size, in cells.
+type webSize struct {
+	Cols int `json:"cols"`
+	Rows int `json:"rows"`
+}
+
+// newWebServer returns a handler serving the page and, at /frames, the
+// world drawn for each viewer.
+func newWebServer(scene Scene, world *sharedWorld) http.Handler {
+	files, _ := fs.Sub(webFiles, "web")
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/frames", func(w http.ResponseWriter, r *http.Request) {
+		serveFrames(w, r, scene, world)
+	})
+	return mux
+}
+
+// newHTTPServer returns a server for handler with timeouts, so slow or
+// silent clients don't hold connections open.
+func newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: handshakeTimeout,
+		ReadTimeout:       webRequestTimeout,
+		WriteTimeout:      webRequestTimeout,
+		IdleTimeout:       webIdleTimeout,
+		MaxHeaderBytes:    8 << 10,
+	}
+}
+
+// serveFrames streams the world to one viewer until they go.
+func serveFrames(w http.ResponseWriter, r *http.Request, scene Scene, world *sharedWorld) {
+	ws, err := acceptWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	// Sizes arrive on their own goroutine; only the latest one matters
+	sizes := make(chan webSize, 1)
+	go func() {
+		defer close(sizes)
+		for {
+			msg, err := ws.readMessage()
+			if err != nil {
+				return
+			}
+			var size webSize
+			if json.Unmarshal(msg, &size) != nil || !viewSizeOK(size.Cols, size.Rows) {
+				continue
+			}
+			select {
+			case <-sizes: // Superseded
+			default:
+			}
+			sizes <- size
+		}
+	}()
+
+	m := initialModel(scene, webDisplay)
+	m.world = world
+	ticker := time.NewTicker(m.frameDelay)
+	defer ticker.Stop()
+	var last string
+	for {
+		var msg tea.Msg
+		select {
+		case size, ok := <-sizes:
+			if !ok {
+				return // The viewer has gone
+			}
+			msg = tea.WindowSizeMsg{Width: size.Cols, Height: size.Rows}
+		case now := <-ticker.C:
+			msg = tickMsg(now)
+		}
+		updated, _ := m.Update(msg)
+		m = updated.(model)
+		if m.termWidth == 0 {
+			continue // Nothing to draw until the page says how big it is
+		}
+		view := m.View()
+		if view == last {
+			continue
+		}
+		last = view
+		if err := ws.writeText(redraw(view)); err != nil {
+			return
+		}
+	}
+}
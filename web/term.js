@@ -0,0 +1,250 @@
+// A small terminal emulator for watching consolerunner in a browser.
+//
+// The server sends each frame as the escape sequences an xterm would get:
+// cursor movement, erasing and SGR colours (the 16 system colours, the 256
+// colour palette and 24-bit colour). This understands those and draws the
+// screen as runs of styled text, and tells the server how many cells fit in
+// the window so the scene is drawn to fit.
+"use strict";
+
+// The 16 system colours, as xterm draws them by default; the rest of the 256
+// colour palette is the 6x6x6 cube and the grey ramp.
+const palette = [
+  "#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+  "#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+];
+(function () {
+  const levels = [0, 95, 135, 175, 215, 255];
+  const hex = (v) => v.toString(16).padStart(2, "0");
+  for (let i = 0; i < 216; i++) {
+    const r = levels[Math.floor(i / 36)], g = levels[Math.floor(i / 6) % 6], b = levels[i % 6];
+    palette.push("#" + hex(r) + hex(g) + hex(b));
+  }
+  for (let i = 0; i < 24; i++) {
+    const v = hex(8 + 10 * i);
+    palette.push("#" + v + v + v);
+  }
+})();
+
+// isWide reports whether a code point takes two cells, as for East Asian
+// wide characters and most emoji.
+function isWide(cp) {
+  return (cp >= 0x1100 && cp <= 0x115f) || (cp >= 0x2e80 && cp <= 0xa4cf && cp !== 0x303f) ||
+    (cp >= 0xac00 && cp <= 0xd7a3) || (cp >= 0xf900 && cp <= 0xfaff) ||
+    (cp >= 0xfe30 && cp <= 0xfe4f) || (cp >= 0xff00 && cp <= 0xff60) ||
+    (cp >= 0xffe0 && cp <= 0xffe6) || (cp >= 0x1f300 && cp <= 0x1f64f) ||
+    (cp >= 0x1f900 && cp <= 0x1f9ff) || (cp >= 0x20000 && cp <= 0x3fffd);
+}
+
+class Terminal {
+  constructor(element) {
+    this.element = element;
+    this.resize(80, 24);
+  }
+
+  // resize clears the screen and makes it cols x rows cells.
+  resize(cols, rows) {
+    this.cols = cols;
+    this.rows = rows;
+    this.x = 0;
+    this.y = 0;
+    this.style = { fg: null, bg: null, bold: false };
+    this.erase();
+  }
+
+  erase() {
+    this.cells = [];
+    for (let y = 0; y < this.rows; y++) {
+      this.cells.push(this.blankRow());
+    }
+  }
+
+  blankRow() {
+    const row = [];
+    for (let x = 0; x < this.cols; x++) {
+      row.push({ ch: " ", fg: null, bg: null, bold: false });
+    }
+    return row;
+  }
+
+  // write interprets output from the server and redraws the screen.
+  write(data) {
+    let i = 0;
+    while (i < data.length) {
+      const ch = data[i];
+      if (ch === "\x1b" && data[i + 1] === "[") {
+        // CSI: parameters, then a final byte
+        let j = i + 2;
+        while (j < data.length && !/[@-~]/.test(data[j])) j++;
+        this.csi(data.slice(i + 2, j), data[j]);
+        i = j + 1;
+      } else if (ch === "\x1b") {
+        i += 2; // Other two-character escapes change nothing drawn
+      } else if (ch === "\r") {
+        this.x = 0;
+        i++;
+      } else if (ch === "\n") {
+        this.y = Math.min(this.y + 1, this.rows - 1);
+        i++;
+      } else {
+        const cp = data.codePointAt(i);
+        this.put(String.fromCodePoint(cp), isWide(cp) ? 2 : 1);
+        i += cp > 0xffff ? 2 : 1;
+      }
+    }
+    this.draw();
+  }
+
+  // put writes a glyph at the cursor and moves it on. The cell under the
+  // second half of a wide glyph is left empty.
+  put(ch, width) {
+    if (this.x + width > this.cols || this.y >= this.rows) {
+      this.x += width; // Off the edge; xterm would wrap, but frames fit
+      return;
+    }
+    const s = this.style;
+    this.cells[this.y][this.x] = { ch: ch, fg: s.fg, bg: s.bg, bold: s.bold };
+    if (width === 2) {
+      this.cells[this.y][this.x + 1] = { ch: "", fg: s.fg, bg: s.bg, bold: s.bold };
+    }
+    this.x += width;
+  }
+
+  csi(params, final) {
+    const args = params.replace(/^\?/, "").split(";").map((p) => (p === "" ? 0 : parseInt(p, 10)));
+    switch (final) {
+      case "H":
+      case "f":
+        this.y = Math.min(Math.max((args[0] || 1) - 1, 0), this.rows - 1);
+        this.x = Math.min(Math.max((args[1] || 1) - 1, 0), this.cols - 1);
+        break;
+      case "J":
+        if (args[0] === 2 || args[0] === 3) this.erase();
+        break;
+      case "K":
+        for (let x = this.x; x < this.cols; x++) {
+          this.cells[this.y][x] = { ch: " ", fg: null, bg: null, bold: false };
+        }
+        break;
+      case "m":
+        this.sgr(args);
+        break;
+    }
+  }
+
+  // sgr applies Select Graphic Rendition parameters to the current style.
+  sgr(args) {
+    const s = this.style;
+    for (let i = 0; i < args.length; i++) {
+      const n = args[i];
+      if (n === 0) {
+        s.fg = s.bg = null;
+        s.bold = false;
+      } else if (n === 1) {
+        s.bold = true;
+      } else if (n === 22) {
+        s.bold = false;
+      } else if (n >= 30 && n <= 37) {
+        s.fg = palette[n - 30];
+      } else if (n >= 90 && n <= 97) {
+        s.fg = palette[n - 90 + 8];
+      } else if (n >= 40 && n <= 47) {
+        s.bg = palette[n - 40];
+      } else if (n >= 100 && n <= 107) {
+        s.bg = palette[n - 100 + 8];
+      } else if (n === 39) {
+        s.fg = null;
+      } else if (n === 49) {
+        s.bg = null;
+      } else if (n === 38 || n === 48) {
+        let color = null;
+        if (args[i + 1] === 5) {
+          color = palette[args[i + 2]] || null;
+          i += 2;
+        } else if (args[i + 1] === 2) {
+          color = "rgb(" + args.slice(i + 2, i + 5).join(",") + ")";
+          i += 4;
+        }
+        if (n === 38) s.fg = color;
+        else s.bg = color;
+      }
+    }
+  }
+
+  // draw shows the screen, one span per run of identically styled cells.
+  draw() {
+    const escape = (t) => t.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;");
+    const lines = [];
+    for (const row of this.cells) {
+      let html = "", run = "", style = null;
+      const flush = () => {
+        if (run === "") return;
+        html += style ? '<span style="' + style + '">' + escape(run) + "</span>" : escape(run);
+        run = "";
+      };
+      for (const c of row) {
+        let css = "";
+        if (c.fg) css += "color:" + c.fg + ";";
+        if (c.bg) css += "background:" + c.bg + ";";
+        if (c.bold) css += "font-weight:bold;";
+        if ((css || null) !== style) {
+          flush();
+          style = css || null;
+        }
+        run += c.ch;
+      }
+      flush();
+      lines.push(html);
+    }
+    this.element.innerHTML = lines.join("\n");
+  }
+}
+
+// cellSize measures one cell of the screen's font, in pixels.
+function cellSize(element) {
+  const probe = document.createElement("span");
+  probe.textContent = "M".repeat(100);
+  probe.style.visibility = "hidden";
+  probe.style.position = "absolute";
+  element.appendChild(probe);
+  const rect = probe.getBoundingClientRect();
+  element.removeChild(probe);
+  return { width: rect.width / 100, height: rect.height };
+}
+
+const screen = document.getElementById("screen");
+const status = document.getElementById("status");
+const term = new Terminal(screen);
+let socket = null;
+
+// fit sizes the terminal to the window and tells the server.
+function fit() {
+  const cell = cellSize(screen);
+  const cols = Math.max(1, Math.floor(window.innerWidth / cell.width));
+  const rows = Math.max(1, Math.floor(window.innerHeight / cell.height));
+  if (cols !== term.cols || rows !== term.rows) {
+    term.resize(cols, rows);
+  }
+  if (socket && socket.readyState === WebSocket.OPEN) {
+    socket.send(JSON.stringify({ cols: cols, rows: rows }));
+  }
+}
+
+function connect() {
+  const url = new URL("frames", window.location.href);
+  url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+  socket = new WebSocket(url);
+  socket.onopen = () => {
+    status.style.display = "none";
+    fit();
+  };
+  socket.onmessage = (event) => term.write(event.data);
+  socket.onclose = () => {
+    status.style.display = "block";
+    setTimeout(connect, 1000);
+  };
+}
+
+window.addEventListener("resize", fit);
+fit();
+connect();
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClientFrame encodes a frame as a browser sends it, masked.
+func wsClientFrame(op wsOpcode, fin bool, payload []byte) []byte {
+	b0 := byte(op)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0, 0x80 | byte(len(payload))} // Tests only send short frames
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, c := range payload {
+		frame = append(frame, c^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame reads an unmasked frame sent by the server.
+func readServerFrame(r *bufio.Reader) (wsOpcode, string, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, "", err
+	}
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, n)
+	_, err := io.ReadFull(r, payload)
+	return wsOpcode(header[0] & 0x0F), string(payload), err
+}
+
+// dialFrames opens a WebSocket to a web server's /frames and asks for a
+// width x height view.
+func dialFrames(t *testing.T, addr string, width, height int) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET /frames HTTP/1.1\r\nHost: "+addr+"\r\nOrigin: http://"+addr+"\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The example from RFC 6455 section 1.3
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); resp.StatusCode != http.StatusSwitchingProtocols || accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake got %s with accept %q", resp.Status, accept)
+	}
+	size := fmt.Sprintf(`{"cols": %d, "rows": %d}`, width, height)
+	conn.Write(wsClientFrame(wsText, true, []byte(size)))
+	return conn, r
+}
+
+func TestWebServesPage(t *testing.T) {
+	srv := httptest.NewServer(newWebServer(defaultScene(), newSharedWorld(defaultScene())))
+	defer srv.Close()
+	for path, want := range map[string]string{"/": `<script src="term.js">`, "/term.js": "class Terminal"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s: %s, want it to contain %q", path, resp.Status, want)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/frames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET /frames without upgrading: %s, want 400", resp.Status)
+	}
+}
+
+func TestWebRejectsOtherOrigins(t *testing.T) {
+	srv := httptest.NewServer(newWebServer(defaultScene(), newSharedWorld(defaultScene())))
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/frames", nil)
+	for k, v := range map[string]string{
+		"Upgrade": "websocket", "Connection": "Upgrade", "Origin": "http://elsewhere.example",
+		"Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==", "Sec-WebSocket-Version": "13",
+	} {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("handshake from another site: %s, want 403", resp.Status)
+	}
+}
+
+func TestWebStreamsWorld(t *testing.T) {
+	srv := httptest.NewServer(newWebServer(defaultScene(), newSharedWorld(defaultScene())))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	// Two viewers at their own sizes
+	for _, size := range [][2]int{{40, 12}, {60, 20}} {
+		_, r := dialFrames(t, addr, size[0], size[1])
+		op, frame, err := readServerFrame(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op != wsText || !strings.HasPrefix(frame, "\x1b[H\x1b[2J") {
+			t.Fatalf("first message %v %q, want a text frame redrawing the screen", op, frame)
+		}
+		if rows := strings.Count(frame, "\r\n") + 1; rows != size[1] {
+			t.Errorf("%dx%d viewer got %d rows", size[0], size[1], rows)
+		}
+		if !strings.Contains(frame, "\x1b[38;5;") {
+			t.Errorf("%dx%d viewer got no colour: %q", size[0], size[1], frame)
+		}
+	}
+}
+
+func TestWebSocketMessages(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	ws := &wsConn{conn: server, r: bufio.NewReader(server)}
+	defer ws.Close()
+
+	// A message in two fragments with a ping in between, then a close
+	go func() {
+		r := bufio.NewReader(client)
+		client.Write(wsClientFrame(wsText, false, []byte(`{"cols":`)))
+		client.Write(wsClientFrame(wsPing, true, []byte("hi")))
+		if op, payload, err := readServerFrame(r); op != wsPong || payload != "hi" {
+			t.Errorf("ping answered with %v %q (%v), want a pong with the same payload", op, payload, err)
+		}
+		client.Write(wsClientFrame(wsContinuation, true, []byte(` 80}`)))
+		client.Write(wsClientFrame(wsClose, true, []byte{0x03, 0xE8}))
+		if op, payload, err := readServerFrame(r); op != wsClose || payload != "\x03\xE8" {
+			t.Errorf("close answered with %v %q (%v), want a close with the same code", op, payload, err)
+		}
+	}()
+
+	msg, err := ws.readMessage()
+	if err != nil || string(msg) != `{"cols": 80}` {
+		t.Errorf("readMessage() = %q, %v; want the fragments joined", msg, err)
+	}
+	if _, err := ws.readMessage(); !errors.Is(err, io.EOF) {
+		t.Errorf("readMessage() after a close error = %v, want EOF", err)
+	}
+}
+
+func TestWebSocketRejectsUnmasked(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	ws := &wsConn{conn: server, r: bufio.NewReader(server)}
+	defer ws.Close()
+	go client.Write([]byte{0x81, 0x02, 'h', 'i'})
+	if _, err := ws.readMessage(); err != errWSProtocol {
+		t.Errorf("readMessage() of an unmasked frame error = %v, want %v", err, errWSProtocol)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- WebSocket connections for the browser view (serve --http) ---
+//
+// Just enough of RFC 6455 for a server streaming text to browsers: the
+// opening handshake, text messages either way, ping/pong and the closing
+// handshake. There are no extensions or subprotocols. Frames from clients
+// are masked, as the RFC requires; frames to them aren't. Browsers say
+// which page opened the connection in the Origin header, and only pages from
+// the same host are let in; clients that aren't browsers don't send one.
+// See https://datatracker.ietf.org/doc/html/rfc6455.
+
+const (
+	wsGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11" // Mixed into the key for the accept header
+	wsMaxMessage = 4096                                   // Longest message a client may send; they only send sizes
+	wsWriteWait  = 5 * time.Second                        // How long a slow viewer can hold up a write
+)
+
+// wsOpcode says what a frame carries.
+type wsOpcode byte
+
+const (
+	wsContinuation wsOpcode = 0x0
+	wsText         wsOpcode = 0x1
+	wsBinary       wsOpcode = 0x2
+	wsClose        wsOpcode = 0x8
+	wsPing         wsOpcode = 0x9
+	wsPong         wsOpcode = 0xA
+)
+
+var (
+	errWSProtocol = errors.New("websocket: protocol error")
+	errWSTooBig   = errors.New("websocket: message too big")
+)
+
+// wsConn is the server's end of a WebSocket connection. Reads must come from
+// one goroutine at a time; writes are safe from any.
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex // Held while writing a frame
+}
+
+// acceptWebSocket completes the opening handshake for a request to upgrade
+// to a WebSocket. If the request isn't one, it replies with an error and
+// returns it.
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "This is a WebSocket endpoint", http.StatusBadRequest)
+		return nil, errWSProtocol
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-origin WebSocket connections aren't allowed", http.StatusForbidden)
+		return nil, errWSProtocol
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errWSProtocol
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Can't upgrade this connection", http.StatusInternalServerError)
+		return nil, errWSProtocol
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{}) // The server's request timeouts don't apply to the stream
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, r: rw.Reader}, nil
+}
+
+// sameOrigin reports whether a request has no Origin header, or one for
+// the host it was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerHas reports whether a comma-separated header lists token, ignoring case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeText sends a text message.
+func (c *wsConn) writeText(s string) error {
+	return c.writeFrame(wsText, []byte(s))
+}
+
+// writeFrame sends one unfragmented frame.
+func (c *wsConn) writeFrame(op wsOpcode, payload []byte) error {
+	header := []byte{0x80 | byte(op), 0} // FIN set: the whole message
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// readMessage returns the next text or binary message, answering pings on
+// the way. It returns io.EOF once the client closes the connection.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			if len(payload) > 2 {
+				payload = payload[:2] // Echo the status code, not the reason
+			}
+			c.writeFrame(wsClose, payload)
+			return nil, io.EOF
+		case wsText, wsBinary:
+			if started {
+				return nil, errWSProtocol // A new message before the last one finished
+			}
+			started = true
+		case wsContinuation:
+			if !started {
+				return nil, errWSProtocol
+			}
+		default:
+			return nil, errWSProtocol
+		}
+		if len(msg)+len(payload) > wsMaxMessage {
+			return nil, errWSTooBig
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks its payload.
+func (c *wsConn) readFrame() (fin bool, op wsOpcode, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = header[0]&0x80 != 0, wsOpcode(header[0]&0x0F)
+	if header[0]&0x70 != 0 || header[1]&0x80 == 0 {
+		return false, 0, nil, errWSProtocol // No extensions, and clients must mask
+	}
+
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxMessage {
+		return false, 0, nil, errWSTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// Close closes the connection without the closing handshake.
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}